@@ -1,16 +1,27 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"image"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"godot-vrt/lib"
 )
 
+var GoldenLogs bool
+var Calibrate int
+var OnlyMissing bool
+var SceneFilters []string
+var Force bool
+var Backup bool
+var DryRun bool
+
 func init() {
 	RootCmd.AddCommand(baselineCmd)
 
@@ -21,7 +32,28 @@ func init() {
 	baselineCmd.MarkFlagRequired("scenes")
 
 	baselineCmd.Flags().StringVarP(&ProjectPath, "project", "p", ".", "path to the project root (only required if you run godot-vrt from a different directory)")
+	baselineCmd.Flags().StringVar(&BaselineDir, "baseline-dir", "", "directory to save the baselines in, mirroring the directory structure of the scenes (e.g. vrt-baselines/), relative from the project root; baselines are saved next to their scenes by default")
+	baselineCmd.Flags().BoolVar(&OnlyMissing, "only-missing", false, "only render scenes that don't have a baseline yet")
+	baselineCmd.Flags().StringArrayVar(&SceneFilters, "scene", nil, "only render scenes whose path or name (relative from the project root) matches this glob, e.g. vrt/menu.tscn or \"vrt/hud*\" (can be repeated)")
+	baselineCmd.Flags().BoolVar(&Force, "force", false, "overwrite existing baselines")
+	baselineCmd.Flags().BoolVar(&Backup, "backup", false, "copy baselines to vrt-results/replaced/ before they're overwritten")
+	baselineCmd.Flags().BoolVar(&DryRun, "dry-run", false, "render from an isolated copy of the project and report which baselines would change and by how much, without changing any files (implies --isolate, and doesn't save to --import-cache)")
 	baselineCmd.Flags().IntVarP(&Frames, "frames", "f", 60, "number of frames to render (default 60)")
+	baselineCmd.Flags().BoolVar(&Screenshot, "screenshot", false, "render a single frame and save it as a .png instead of a video (useful for static scenes)")
+	baselineCmd.Flags().IntVar(&SkipFrames, "skip-frames", 0, "number of frames at the start of each video that --dry-run and --calibrate ignore when comparing (the full video is still recorded)")
+	baselineCmd.Flags().IntVar(&WarmupFrames, "warmup-frames", 0, "number of frames to render before taking the screenshot in --screenshot mode")
+	baselineCmd.Flags().IntVar(&Calibrate, "calibrate", 0, "render each scene this many times and save the noise between the renders as <scene>.noise.png, which test tolerates")
+	baselineCmd.Flags().BoolVar(&GoldenLogs, "golden-logs", false, "save godot's normalized console output as <scene>.log.golden, which test compares against")
+	baselineCmd.Flags().StringVarP(&ConfigFile, "config", "c", "", "path to a config file with per-scene settings such as variants (defaults to godot-vrt.json in the project root if it exists)")
+	baselineCmd.Flags().StringSliceVar(&Locales, "locales", nil, "comma separated list of locales to render each scene in (e.g. en,de,ja)")
+	baselineCmd.Flags().DurationVar(&RenderTimeout, "timeout", 5*time.Minute, "maximum time to render a single scene before godot gets killed (0 disables the timeout)")
+	baselineCmd.Flags().BoolVar(&ImportAssets, "import", false, "let godot import the project's assets once before rendering (useful for fresh checkouts without a .godot directory)")
+	baselineCmd.Flags().StringVar(&ImportCache, "import-cache", "", "directory to cache the imported .godot directory in, keyed by a hash of the project's assets (implies --import)")
+	baselineCmd.Flags().BoolVar(&Isolate, "isolate", false, "render from a copy of the project with a throwaway user:// directory, so that renders can't modify the project or leak saved state")
+	baselineCmd.Flags().BoolVar(&AllowUnsupportedGodot, "allow-unsupported-godot", false, "use Godot versions that aren't supported, such as custom engine builds and release candidates")
+	baselineCmd.Flags().BoolVar(&VirtualDisplay, "virtual-display", false, "start a virtual X display (Xvfb) and render on it with Godot's Compatibility renderer and Mesa's software rasterizer (for CI machines without a display or GPU)")
+	baselineCmd.Flags().BoolVar(&Batch, "batch", false, "render all scenes in a single godot process (one per locale) instead of starting godot for every scene")
+	baselineCmd.Flags().BoolVar(&Checkpoints, "checkpoints", false, "inject the VRT autoload so that scenes can save named screenshots with VRT.checkpoint(\"name\"), which are compared against <scene>.checkpoints/<name>.png")
 
 	// FPS only affects the fps of the video, but not the speed at which we render it. Speed
 	// might only be affected by the hardware speed.
@@ -37,29 +69,58 @@ var baselineCmd = &cobra.Command{
 			fmt.Println("Frames must be greater than 0")
 			os.Exit(1)
 		}
+		if WarmupFrames < 0 {
+			fmt.Println("Warmup frames must not be negative")
+			os.Exit(1)
+		}
+		if SkipFrames < 0 {
+			fmt.Println("Skip frames must not be negative")
+			os.Exit(1)
+		}
+		if Calibrate == 1 || Calibrate < 0 {
+			fmt.Println("Calibrate must be at least 2 (or 0 to disable calibration)")
+			os.Exit(1)
+		}
+		for _, filter := range SceneFilters {
+			if _, err := filepath.Match(filter, ""); err != nil {
+				fmt.Printf("Invalid scene filter %q: %v\n", filter, err)
+				os.Exit(1)
+			}
+		}
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		ProjectPath = lib.WithFolderSuffix(ProjectPath)
 
-		if err := lib.Validate(GodotExecutable, ProjectPath); err != nil {
+		stopDisplay, err := startVirtualDisplay()
+		stopDisplay = onExit(stopDisplay)
+		if err != nil {
 			fmt.Println(err)
 			if !OmitExitCode {
-				os.Exit(1)
+				exit(cmd.Context(), 1)
 			}
 		}
+		defer stopDisplay()
 
-		err := renderScenes()
+		godotExecutableVersion, err = lib.Validate(GodotExecutable, ProjectPath, AllowUnsupportedGodot, virtualDisplay)
 		if err != nil {
 			fmt.Println(err)
 			if !OmitExitCode {
-				os.Exit(1)
+				exit(cmd.Context(), 1)
+			}
+		}
+
+		err = renderScenes(cmd.Context())
+		if err != nil {
+			fmt.Println(err)
+			if !OmitExitCode {
+				exit(cmd.Context(), 1)
 			}
 		}
 	},
 }
 
-func renderScenes() error {
+func renderScenes(ctx context.Context) error {
 	// list all sceneFiles at config.Scenes (that's a glob)
 	sceneFiles, err := filepath.Glob(ProjectPath + ScenesGlob)
 	if err != nil {
@@ -69,23 +130,354 @@ func renderScenes() error {
 		return fmt.Errorf("search for files at %s yielded 0 results", ProjectPath+ScenesGlob)
 	}
 
-	for _, file := range sceneFiles {
-		f, err := filepath.Abs(file)
+	if err := verifyLocales(); err != nil {
+		return err
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	targets, err := selectTargets(sceneTargets(sceneFiles, config))
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		fmt.Println("No baselines to render")
+		return nil
+	}
+
+	tmpDir, cleanupTmpDir := lib.InitTmpDir()
+	cleanupTmpDir = onExit(cleanupTmpDir)
+	defer cleanupTmpDir()
+	if DryRun {
+		// Importing assets and rendering can write to the project, so a dry run always renders from a copy.
+		Isolate = true
+	}
+	if err := prepareProject(ctx, tmpDir); err != nil {
+		return err
+	}
+	if DryRun {
+		return dryRunBaselines(ctx, targets, tmpDir)
+	}
+
+	if err := createBaselineDir(); err != nil {
+		return err
+	}
+	for _, target := range targets {
+		if err := createImagesDir(target); err != nil {
+			return err
+		}
+	}
+	if Backup {
+		for _, target := range targets {
+			if err := backupBaseline(target); err != nil {
+				return err
+			}
+		}
+	}
+	baselineFiles := map[string]string{}
+	for _, target := range targets {
+		f, err := filepath.Abs(target.BaselineFile())
 		if err != nil {
 			return fmt.Errorf("error getting absolute path: %v", err)
 		}
-		b, err := lib.RenderScene(lib.RenderSceneArgs{
-			SceneFileFromProjectRoot: strings.Replace(file, ProjectPath, "", 1),
-			OutputFile:               strings.Replace(f, ".tscn", ".avi", 1),
-			GodotBinary:              GodotExecutable,
-			Verbose:                  Verbose,
-			Frames:                   Frames,
-			ProjectPath:              ProjectPath,
-		})
+		baselineFiles[target.Name()] = f
+	}
+
+	renders, err := renderTargets(ctx, targets, tmpDir, func(target sceneTarget) string {
+		return baselineFiles[target.Name()]
+	})
+	if err != nil {
+		return fmt.Errorf("error rendering files: %v", err)
+	}
+	for i, target := range targets {
+		b, err := renders[i].Result, renders[i].Err
 		if err != nil {
 			return fmt.Errorf("error rendering file: %v", err)
 		}
-		fmt.Println("Rendered baseline: " + b)
+		fmt.Println("Rendered baseline: " + b.OutputFile)
+		if err := writeMetadata(target, b.OutputFile); err != nil {
+			return err
+		}
+
+		if Checkpoints {
+			names, err := lib.ListCheckpoints(checkpointsDir(b.OutputFile))
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Saved checkpoints: %v\n", names)
+		}
+
+		if GoldenLogs {
+			goldenLog := goldenLogFile(baselineFiles[target.Name()])
+			if err := lib.WriteGoldenLog(goldenLog, lib.NormalizeGodotLog(b.Stdout, renderProjectPath)); err != nil {
+				return err
+			}
+			fmt.Println("Saved golden log: " + goldenLog)
+		} else {
+			// test compares against any golden log it finds, so an outdated one would fail the new baseline
+			goldenLog := goldenLogFile(baselineFiles[target.Name()])
+			if err := os.Remove(goldenLog); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("error removing outdated golden log: %v", err)
+			}
+		}
+	}
+
+	return calibrateNoise(ctx, targets, renders, tmpDir)
+}
+
+// selectTargets applies --scene and --only-missing, and refuses to overwrite existing baselines without --force.
+func selectTargets(targets []sceneTarget) ([]sceneTarget, error) {
+	var selected []sceneTarget
+	var existing []string
+	for _, target := range targets {
+		if !matchesSceneFilters(target) {
+			continue
+		}
+		if _, err := os.Stat(target.BaselineFile()); err == nil {
+			if OnlyMissing {
+				continue
+			}
+			existing = append(existing, target.BaselineFile())
+		}
+		selected = append(selected, target)
+	}
+	// a dry run doesn't overwrite anything
+	if len(existing) > 0 && !Force && !DryRun {
+		return nil, fmt.Errorf("refusing to overwrite %d existing baselines %v: pass --force to overwrite them, --only-missing to skip them, "+
+			"or --scene to select the scenes to update", len(existing), existing)
+	}
+	return selected, nil
+}
+
+// matchesSceneFilters reports whether the scene file or the name of the target matches any of the --scene globs.
+// Without filters, all targets match.
+func matchesSceneFilters(target sceneTarget) bool {
+	if len(SceneFilters) == 0 {
+		return true
+	}
+	for _, filter := range SceneFilters {
+		for _, name := range []string{target.SceneFileFromProjectRoot(), target.NameFromProjectRoot()} {
+			// the patterns are validated in Args
+			if matched, _ := filepath.Match(filter, name); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// backupDir holds the baselines that --backup copied before they were overwritten. prune keeps them regardless of their
+// age.
+const backupDir = "vrt-results/replaced/"
+
+// backupBaseline copies the existing baseline of a target and the files that belong to it to backupDir.
+func backupBaseline(target sceneTarget) error {
+	baseline := target.BaselineFile()
+	if _, err := os.Stat(baseline); os.IsNotExist(err) {
+		return nil
+	}
+	backup := backupDir + target.NameFromProjectRoot() + target.Ext()
+	files := append([]string{baseline}, baselineSidecars(baseline)...)
+	backups := append([]string{backup}, baselineSidecars(backup)...)
+	for i := range files {
+		if _, err := os.Stat(files[i]); os.IsNotExist(err) {
+			continue
+		}
+		// CopyPath merges directories, so clear old backups of checkpoints first.
+		if err := os.RemoveAll(backups[i]); err != nil {
+			return fmt.Errorf("error removing old backup: %v", err)
+		}
+		if err := lib.CopyPath(files[i], backups[i]); err != nil {
+			return fmt.Errorf("error backing up %s: %v", files[i], err)
+		}
+	}
+	fmt.Println("Backed up " + baseline + " to " + backup)
+	return nil
+}
+
+// dryRunBaselines renders the targets into tmpDir and compares them against their baselines the same way test does,
+// but only reports the baselines that would change.
+func dryRunBaselines(ctx context.Context, targets []sceneTarget, tmpDir string) error {
+	renders, err := renderTargets(ctx, targets, tmpDir, func(target sceneTarget) string {
+		return fmt.Sprintf("%s%s_dry_run%s", tmpDir, target.Name(), target.Ext())
+	})
+	if err != nil {
+		return fmt.Errorf("error rendering files: %v", err)
+	}
+
+	var changed, created []string
+	unchanged := 0
+	for i, target := range targets {
+		b, err := renders[i].Result, renders[i].Err
+		if err != nil {
+			return fmt.Errorf("error rendering file: %v", err)
+		}
+		baseline := target.BaselineFile()
+		if _, err := os.Stat(baseline); os.IsNotExist(err) {
+			created = append(created, baseline)
+			continue
+		}
+		changes, err := baselineChanges(target, b, baseline, tmpDir)
+		if err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			unchanged++
+			continue
+		}
+		changed = append(changed, fmt.Sprintf("%s: %s", baseline, strings.Join(changes, "; ")))
+	}
+
+	fmt.Println("Dry run, no baselines were changed")
+	if len(changed) > 0 {
+		fmt.Printf("✏️ %d baselines would change:\n", len(changed))
+		for _, c := range changed {
+			fmt.Println("  " + c)
+		}
+	}
+	if len(created) > 0 {
+		fmt.Printf("🆕 %d baselines would be created:\n", len(created))
+		for _, c := range created {
+			fmt.Println("  " + c)
+		}
+	}
+	fmt.Printf("%d baselines would stay the same\n", unchanged)
+	return nil
+}
+
+// baselineChanges describes how a render differs from the existing baseline of a target. The baseline is compared
+// from a copy in tmpDir, so that extracting its frames doesn't leave files next to it.
+func baselineChanges(target sceneTarget, rendered lib.RenderResult, baseline, tmpDir string) ([]string, error) {
+	baselineCopy := fmt.Sprintf("%s%s_baseline%s", tmpDir, target.Name(), target.Ext())
+	files := append([]string{baseline}, baselineSidecars(baseline)...)
+	copies := append([]string{baselineCopy}, baselineSidecars(baselineCopy)...)
+	for i := range files {
+		if _, err := os.Stat(files[i]); os.IsNotExist(err) {
+			continue
+		}
+		if err := lib.CopyPath(files[i], copies[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	var changes []string
+	differs, err := rendersDifferFromBaseline(target, rendered.OutputFile, baselineCopy, fmt.Sprintf("%s%s_dry_run_diff.avi", tmpDir, target.Name()))
+	if err != nil {
+		return nil, fmt.Errorf("error comparing %s: %v", baseline, err)
+	}
+	if differs {
+		var noise *image.Gray
+		if _, err := os.Stat(noiseMapFile(baselineCopy)); err == nil {
+			if noise, err = lib.LoadNoiseMap(noiseMapFile(baselineCopy)); err != nil {
+				return nil, err
+			}
+		}
+		change, err := lib.MeasureChange(rendered.OutputFile, baselineCopy, noise, target.Frames(), target.CompareFrames, Verbose)
+		if err != nil {
+			return nil, fmt.Errorf("error measuring the change of %s: %v", baseline, err)
+		}
+		changes = append(changes, change.String())
+	}
+
+	golden, err := os.ReadFile(goldenLogFile(baselineCopy))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error reading golden log: %v", err)
+	}
+	switch {
+	case GoldenLogs && os.IsNotExist(err):
+		changes = append(changes, "new golden log")
+	case GoldenLogs && string(golden) != lib.NormalizeGodotLog(rendered.Stdout, renderProjectPath):
+		changes = append(changes, "golden log changed")
+	case !GoldenLogs && err == nil:
+		// without --golden-logs, baseline removes the outdated golden log
+		changes = append(changes, "golden log removed")
+	}
+
+	if Checkpoints {
+		comparison, err := lib.CompareCheckpoints(checkpointsDir(rendered.OutputFile), checkpointsDir(baselineCopy))
+		if err != nil {
+			return nil, fmt.Errorf("error comparing checkpoints: %v", err)
+		}
+		if len(comparison.Changed) > 0 {
+			changes = append(changes, fmt.Sprintf("checkpoints changed %v", comparison.Changed))
+		}
+		if len(comparison.Missing) > 0 {
+			changes = append(changes, fmt.Sprintf("checkpoints removed %v", comparison.Missing))
+		}
+		if len(comparison.New) > 0 {
+			changes = append(changes, fmt.Sprintf("checkpoints added %v", comparison.New))
+		}
+	}
+	return changes, nil
+}
+
+// writeMetadata saves how a baseline was rendered next to it, so that test can tell if it renders in a different
+// environment.
+func writeMetadata(target sceneTarget, baselineFile string) error {
+	sceneHash, err := lib.FileHash(target.SceneFile)
+	if err != nil {
+		return err
+	}
+	baselineHash, err := lib.FileHash(baselineFile)
+	if err != nil {
+		return err
+	}
+	environment := renderEnvironment
+	if target.Screenshot {
+		// screenshots are pngs in both modes
+		environment.Encoder = ""
+	}
+	return lib.WriteMetadata(lib.MetadataFile(baselineFile), lib.BaselineMetadata{
+		Environment: environment,
+		Frames:      target.Frames(),
+		Hashes:      lib.ContentHashes{Scene: sceneHash, Baseline: baselineHash},
+	})
+}
+
+// calibrateNoise renders each target Calibrate-1 more times, and saves the noise between these renders and the
+// baseline next to the baseline. Without --calibrate, noise maps of earlier baselines are removed, because they
+// don't match the new baseline anymore.
+func calibrateNoise(ctx context.Context, targets []sceneTarget, baselines []targetRender, tmpDir string) error {
+	if Calibrate < 2 {
+		for i := range targets {
+			noiseFile := noiseMapFile(baselines[i].Result.OutputFile)
+			if err := os.Remove(noiseFile); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("error removing outdated noise map: %v", err)
+			}
+		}
+		return nil
+	}
+
+	runs := make([][]targetRender, Calibrate-1)
+	for r := range runs {
+		fmt.Printf("Rendering calibration run %d of %d\n", r+2, Calibrate)
+		var err error
+		runs[r], err = renderTargets(ctx, targets, tmpDir, func(target sceneTarget) string {
+			return fmt.Sprintf("%s%s_calibration%d%s", tmpDir, target.Name(), r, target.Ext())
+		})
+		if err != nil {
+			return fmt.Errorf("error rendering files: %v", err)
+		}
+	}
+
+	for i, target := range targets {
+		renders := []string{baselines[i].Result.OutputFile}
+		for r := range runs {
+			if err := runs[r][i].Err; err != nil {
+				return fmt.Errorf("error rendering file: %v", err)
+			}
+			renders = append(renders, runs[r][i].Result.OutputFile)
+		}
+		noise, err := lib.MeasureNoise(renders, target.Frames(), target.CompareFrames, Verbose)
+		if err != nil {
+			return fmt.Errorf("error measuring noise: %v", err)
+		}
+		noiseFile := noiseMapFile(baselines[i].Result.OutputFile)
+		if err := lib.SavePNG(noise, noiseFile); err != nil {
+			return err
+		}
+		fmt.Printf("Saved noise map: %s (%d unstable pixels)\n", noiseFile, lib.UnstablePixels(noise))
 	}
 	return nil
 }
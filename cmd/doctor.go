@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"godot-vrt/lib"
+)
+
+func init() {
+	RootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().StringVarP(&GodotExecutable, "godot", "g", "", "path to the godot executable (e.g. /usr/local/bin/godot)")
+	doctorCmd.MarkFlagRequired("godot")
+
+	doctorCmd.Flags().StringVarP(&ScenesGlob, "scenes", "s", "", "glob path to the .tscn files, relative from the godot project root (e.g. scenes-vrt/*.tscn)")
+	doctorCmd.Flags().StringVarP(&ProjectPath, "project", "p", ".", "path to the project root (only required if you run godot-vrt from a different directory)")
+	doctorCmd.Flags().StringVar(&BaselineDir, "baseline-dir", "", "directory the baselines are saved in, mirroring the directory structure of the scenes (e.g. vrt-baselines/), relative from the project root")
+	doctorCmd.Flags().BoolVar(&Screenshot, "screenshot", false, "check for .png baselines instead of videos")
+	doctorCmd.Flags().StringVarP(&ConfigFile, "config", "c", "", "path to a config file with per-scene settings such as variants (defaults to godot-vrt.json in the project root if it exists)")
+	doctorCmd.Flags().StringSliceVar(&Locales, "locales", nil, "comma separated list of locales to render each scene in (e.g. en,de,ja)")
+	doctorCmd.Flags().BoolVar(&AllowUnsupportedGodot, "allow-unsupported-godot", false, "use Godot versions that aren't supported, such as custom engine builds and release candidates")
+	doctorCmd.Flags().BoolVar(&VirtualDisplay, "virtual-display", false, "check rendering on a virtual X display (Xvfb) instead of the current display")
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Checks the environment and the project for everything godot-vrt needs, and suggests fixes",
+	Run: func(cmd *cobra.Command, args []string) {
+		ProjectPath = lib.WithFolderSuffix(ProjectPath)
+
+		failed := false
+		for _, check := range diagnose() {
+			fmt.Println(check)
+			if check.Status == lib.CheckFailed {
+				failed = true
+			}
+		}
+		if failed {
+			fmt.Println("❌ Some checks failed")
+			if !OmitExitCode {
+				os.Exit(1)
+			}
+			return
+		}
+		fmt.Println("✅ godot-vrt is ready to go")
+	},
+}
+
+func diagnose() []lib.Check {
+	checks := []lib.Check{lib.CheckGodot(GodotExecutable, AllowUnsupportedGodot)}
+	checks = append(checks, lib.CheckFFmpeg()...)
+
+	stopDisplay, err := startVirtualDisplay()
+	defer stopDisplay()
+	if err != nil {
+		checks = append(checks, lib.Check{Name: "Display", Status: lib.CheckFailed, Detail: err.Error(),
+			Fix: "install Xvfb (e.g. apt install xvfb), or run without --virtual-display on a machine with a display"})
+	} else {
+		checks = append(checks, lib.CheckDisplay(GodotExecutable, virtualDisplay))
+	}
+
+	checks = append(checks, lib.CheckDiskSpace("."))
+	checks = append(checks, lib.CheckProjectRenderer(ProjectPath, VirtualDisplay))
+	return append(checks, checkScenes()...)
+}
+
+// checkScenes checks that the configuration and the scenes glob resolve to existing scenes, and that every scene
+// (and variant) has a baseline.
+func checkScenes() []lib.Check {
+	if err := verifyLocales(); err != nil {
+		return []lib.Check{{Name: "Locales", Status: lib.CheckFailed, Detail: err.Error(), Fix: "use locales like en, de or pt_BR"}}
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return []lib.Check{{Name: "Config", Status: lib.CheckFailed, Detail: err.Error(), Fix: "fix the config file"}}
+	}
+	var checks []lib.Check
+	for scene := range config.Scenes {
+		if _, err := os.Stat(ProjectPath + scene); err != nil {
+			checks = append(checks, lib.Check{Name: "Config", Status: lib.CheckWarning,
+				Detail: fmt.Sprintf("configured scene %s doesn't exist", scene),
+				Fix:    "fix the scene's path in the config file (relative from the project root), or remove it"})
+		}
+	}
+	if _, err := loadQuarantine(config); err != nil {
+		checks = append(checks, lib.Check{Name: "Quarantine", Status: lib.CheckFailed, Detail: err.Error(), Fix: "fix the quarantine list"})
+	}
+
+	if ScenesGlob == "" {
+		return append(checks, lib.Check{Name: "Scenes", Status: lib.CheckWarning, Detail: "not checked",
+			Fix: "pass --scenes to check that the scenes and their baselines resolve"})
+	}
+	sceneFiles, err := filepath.Glob(ProjectPath + ScenesGlob)
+	if err != nil || len(sceneFiles) == 0 {
+		return append(checks, lib.Check{Name: "Scenes", Status: lib.CheckFailed,
+			Detail: fmt.Sprintf("search for files at %s yielded 0 results", ProjectPath+ScenesGlob),
+			Fix:    "check --scenes and --project, the scenes glob is relative from the project root"})
+	}
+
+	targets := sceneTargets(sceneFiles, config)
+	checks = append(checks, lib.Check{Name: "Scenes", Detail: fmt.Sprintf("%d scenes, %d renders", len(sceneFiles), len(targets))})
+	mapping, err := mapBaselines(targets, config)
+	if err != nil {
+		return append(checks, lib.Check{Name: "Baselines", Status: lib.CheckFailed, Detail: err.Error()})
+	}
+	if len(mapping.Orphans) > 0 {
+		checks = append(checks, lib.Check{Name: "Orphaned baselines", Status: lib.CheckWarning,
+			Detail: fmt.Sprintf("%v have no scene", mapping.Orphans),
+			Fix:    "delete them, or move them to the baseline location of their renamed scene"})
+	}
+	if len(mapping.Missing) > 0 {
+		var missing []string
+		for _, target := range mapping.Missing {
+			missing = append(missing, target.BaselineFile())
+		}
+		return append(checks, lib.Check{Name: "Baselines", Status: lib.CheckFailed,
+			Detail: fmt.Sprintf("%d of %d missing: %v", len(missing), len(targets), missing),
+			Fix:    "render the missing baselines with godot-vrt baseline"})
+	}
+	return append(checks, lib.Check{Name: "Baselines", Detail: fmt.Sprintf("all %d baselines exist", len(targets))})
+}
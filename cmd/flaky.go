@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"godot-vrt/lib"
+)
+
+var Runs int
+
+// maxReportedRegions limits how many unstable regions are printed per scene.
+const maxReportedRegions = 5
+
+func init() {
+	RootCmd.AddCommand(flakyCmd)
+
+	flakyCmd.Flags().StringVarP(&GodotExecutable, "godot", "g", "", "path to the godot executable (e.g. /usr/local/bin/godot)")
+	flakyCmd.MarkFlagRequired("godot")
+
+	flakyCmd.Flags().StringVarP(&ScenesGlob, "scenes", "s", "", "glob path to the .tscn files, relative from the godot project root (e.g. scenes-vrt/*.tscn)")
+	flakyCmd.MarkFlagRequired("scenes")
+
+	flakyCmd.Flags().StringVarP(&ProjectPath, "project", "p", ".", "path to the project root (only required if you run godot-vrt from a different directory)")
+	flakyCmd.Flags().IntVarP(&Runs, "runs", "r", 5, "number of times each scene is rendered")
+	flakyCmd.Flags().IntVarP(&Frames, "frames", "f", 60, "number of frames to render")
+	flakyCmd.Flags().IntVar(&SkipFrames, "skip-frames", 0, "number of frames at the start of each video that are ignored when comparing (the full video is still recorded)")
+	flakyCmd.Flags().BoolVar(&Screenshot, "screenshot", false, "render a single frame and save it as a .png instead of a video (useful for static scenes)")
+	flakyCmd.Flags().IntVar(&WarmupFrames, "warmup-frames", 0, "number of frames to render before taking the screenshot in --screenshot mode")
+	flakyCmd.Flags().StringVarP(&ConfigFile, "config", "c", "", "path to a config file with per-scene settings such as variants (defaults to godot-vrt.json in the project root if it exists)")
+	flakyCmd.Flags().StringSliceVar(&Locales, "locales", nil, "comma separated list of locales to render each scene in (e.g. en,de,ja)")
+	flakyCmd.Flags().DurationVar(&RenderTimeout, "timeout", 5*time.Minute, "maximum time to render a single scene before godot gets killed (0 disables the timeout)")
+	flakyCmd.Flags().BoolVar(&ImportAssets, "import", false, "let godot import the project's assets once before rendering (useful for fresh checkouts without a .godot directory)")
+	flakyCmd.Flags().StringVar(&ImportCache, "import-cache", "", "directory to cache the imported .godot directory in, keyed by a hash of the project's assets (implies --import)")
+	flakyCmd.Flags().BoolVar(&Isolate, "isolate", false, "render from a copy of the project with a throwaway user:// directory, so that renders can't modify the project or leak saved state")
+	flakyCmd.Flags().BoolVar(&AllowUnsupportedGodot, "allow-unsupported-godot", false, "use Godot versions that aren't supported, such as custom engine builds and release candidates")
+	flakyCmd.Flags().BoolVar(&VirtualDisplay, "virtual-display", false, "start a virtual X display (Xvfb) and render on it with Godot's Compatibility renderer and Mesa's software rasterizer (for CI machines without a display or GPU)")
+	flakyCmd.Flags().BoolVar(&Batch, "batch", false, "render all scenes in a single godot process (one per locale) instead of starting godot for every scene")
+}
+
+var flakyCmd = &cobra.Command{
+	Use:   "flaky",
+	Short: "Renders scenes multiple times to find scenes that don't render the same way every time",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if Runs < 2 {
+			fmt.Println("Runs must be at least 2")
+			os.Exit(1)
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		ProjectPath = lib.WithFolderSuffix(ProjectPath)
+
+		stopDisplay, err := startVirtualDisplay()
+		stopDisplay = onExit(stopDisplay)
+		if err != nil {
+			fmt.Println(err)
+			if !OmitExitCode {
+				exit(cmd.Context(), 1)
+			}
+		}
+		defer stopDisplay()
+
+		godotExecutableVersion, err = lib.Validate(GodotExecutable, ProjectPath, AllowUnsupportedGodot, virtualDisplay)
+		if err != nil {
+			fmt.Println(err)
+			if !OmitExitCode {
+				exit(cmd.Context(), 1)
+			}
+		}
+
+		flakyScenes, err := detectFlakiness(cmd.Context())
+		if err != nil {
+			fmt.Println(err)
+			if !OmitExitCode {
+				exit(cmd.Context(), 1)
+			}
+		}
+		if len(flakyScenes) > 0 {
+			fmt.Printf("⚠️ Nondeterministic scenes: %v\n", flakyScenes)
+			if !OmitExitCode {
+				exit(cmd.Context(), 50)
+			}
+			return
+		}
+		fmt.Println("✅ All scenes rendered the same way every time")
+	},
+}
+
+func detectFlakiness(ctx context.Context) ([]string, error) {
+	sceneFiles, err := filepath.Glob(ProjectPath + ScenesGlob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files at %s: %v", ProjectPath+ScenesGlob, err)
+	}
+	if len(sceneFiles) == 0 {
+		return nil, fmt.Errorf("search for files at %s yielded 0 results", ProjectPath+ScenesGlob)
+	}
+
+	if err := verifyLocales(); err != nil {
+		return nil, err
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	targets := sceneTargets(sceneFiles, config)
+
+	tmpDir, cleanupTmpDir := lib.InitTmpDir()
+	cleanupTmpDir = onExit(cleanupTmpDir)
+	defer cleanupTmpDir()
+	if err := prepareProject(ctx, tmpDir); err != nil {
+		return nil, err
+	}
+
+	// runs[r][i] is the r-th render of targets[i]
+	runs := make([][]targetRender, Runs)
+	for r := range runs {
+		fmt.Printf("Rendering run %d of %d\n", r+1, Runs)
+		runs[r], err = renderTargets(ctx, targets, tmpDir, func(target sceneTarget) string {
+			return fmt.Sprintf("%s%s_run%d%s", tmpDir, target.Name(), r, target.Ext())
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error rendering files: %v", err)
+		}
+	}
+
+	var flakyScenes []string
+	for i, target := range targets {
+		renders, err := collectRuns(target, runs, i)
+		if err != nil {
+			return nil, err
+		}
+		if renders == nil {
+			flakyScenes = append(flakyScenes, target.Name())
+			continue
+		}
+		flaky, err := reportFlakiness(target, renders, tmpDir)
+		if err != nil {
+			return nil, err
+		}
+		if flaky {
+			flakyScenes = append(flakyScenes, target.Name())
+		}
+	}
+	return flakyScenes, nil
+}
+
+// collectRuns returns the rendered files of all runs of a target, or nil if the target timed out in some of them.
+func collectRuns(target sceneTarget, runs [][]targetRender, i int) ([]string, error) {
+	var renders []string
+	for r := range runs {
+		render := runs[r][i]
+		var timeoutErr *lib.TimeoutError
+		if errors.As(render.Err, &timeoutErr) {
+			fmt.Printf("⚠️ %s timed out in run %d\n", target.Name(), r+1)
+			return nil, nil
+		}
+		if render.Err != nil {
+			return nil, fmt.Errorf("error rendering file: %v", render.Err)
+		}
+		renders = append(renders, render.Result.OutputFile)
+	}
+	return renders, nil
+}
+
+// reportFlakiness diffs all renders of a target pairwise, and prints the unstable regions and a suggested ignore mask
+// if they differ.
+func reportFlakiness(target sceneTarget, renders []string, tmpDir string) (bool, error) {
+	sceneName := target.Name()
+	pairs, differingPairs := 0, 0
+	for a := 0; a < len(renders); a++ {
+		for b := a + 1; b < len(renders); b++ {
+			pairs++
+			differ, err := rendersDiffer(target, renders[b], renders[a], fmt.Sprintf("%s%s_diff%d_%d.avi", tmpDir, sceneName, a, b))
+			if err != nil {
+				return false, fmt.Errorf("error generating diff: %v", err)
+			}
+			if differ {
+				differingPairs++
+			}
+		}
+	}
+	if differingPairs == 0 {
+		fmt.Printf("✅ %s rendered the same way in all %d runs\n", sceneName, len(renders))
+		return false, nil
+	}
+
+	noise, err := lib.MeasureNoise(renders, target.Frames(), target.CompareFrames, Verbose)
+	if err != nil {
+		return false, fmt.Errorf("error measuring noise: %v", err)
+	}
+	unstable := lib.UnstablePixels(noise)
+	fmt.Printf("⚠️ %s is nondeterministic: %d of %d pairs of runs differ, %d pixels (%.2f%%) changed\n",
+		sceneName, differingPairs, pairs, unstable, 100*float64(unstable)/float64(len(noise.Pix)))
+
+	regions := lib.UnstableRegions(noise)
+	for i, region := range regions {
+		if i == maxReportedRegions {
+			fmt.Printf("  ... and %d more regions\n", len(regions)-maxReportedRegions)
+			break
+		}
+		fmt.Printf("  unstable region at x=%d y=%d (%dx%d)\n", region.Min.X, region.Min.Y, region.Dx(), region.Dy())
+	}
+
+	maskFile := "vrt-results/" + sceneName + ".ignore-mask.png"
+	if err := lib.SavePNG(lib.IgnoreMask(noise), maskFile); err != nil {
+		return false, err
+	}
+	fmt.Println("  suggested ignore mask (white pixels are unstable): " + maskFile)
+	return true, nil
+}
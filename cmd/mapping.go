@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"godot-vrt/lib"
+)
+
+// baselineMapping pairs targets with the baselines that exist for them.
+type baselineMapping struct {
+	Matched []sceneTarget
+	// Missing lists the targets without a baseline.
+	Missing []sceneTarget
+	// Orphans are baselines without a scene, e.g. because the scene was renamed or deleted.
+	Orphans []string
+}
+
+// mapBaselines looks up the baseline of each target (see sceneTarget.BaselineFile), and searches the places that
+// baselines are saved in for baselines whose scene doesn't exist anymore. Baselines of existing scenes that aren't
+// targets of this run (e.g. other locales, or scenes outside of --scenes) aren't orphans.
+func mapBaselines(targets []sceneTarget, config lib.Config) (baselineMapping, error) {
+	var mapping baselineMapping
+	// Paths are compared as absolute paths, so that ./vrt/menu.avi and vrt/menu.avi are the same baseline.
+	expected := map[string]bool{}
+	for _, target := range targets {
+		baseline, err := filepath.Abs(target.BaselineFile())
+		if err != nil {
+			return mapping, fmt.Errorf("error getting absolute path: %v", err)
+		}
+		expected[baseline] = true
+		if _, err := os.Stat(baseline); err != nil {
+			mapping.Missing = append(mapping.Missing, target)
+		} else {
+			mapping.Matched = append(mapping.Matched, target)
+		}
+	}
+
+	candidates, err := baselineCandidates(targets)
+	if err != nil {
+		return mapping, err
+	}
+	for _, candidate := range candidates {
+		abs, err := filepath.Abs(candidate)
+		if err != nil {
+			return mapping, fmt.Errorf("error getting absolute path: %v", err)
+		}
+		if !expected[abs] && !baselineSceneExists(candidate, config) {
+			mapping.Orphans = append(mapping.Orphans, candidate)
+		}
+	}
+	slices.Sort(mapping.Orphans)
+	return mapping, nil
+}
+
+// baselineCandidates lists the files that look like baselines: the files matching --baseline if it's set, all
+// baselines in --baseline-dir, or otherwise the baselines in the directories of the targets' baselines.
+func baselineCandidates(targets []sceneTarget) ([]string, error) {
+	if BaselineGlob != "" {
+		files, err := filepath.Glob(ProjectPath + BaselineGlob)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files at %s: %v", ProjectPath+BaselineGlob, err)
+		}
+		return files, nil
+	}
+
+	var candidates []string
+	if BaselineDir != "" {
+		err := filepath.WalkDir(baselineDirPath(), func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() && strings.HasSuffix(d.Name(), ".checkpoints") {
+				return filepath.SkipDir
+			}
+			if !d.IsDir() && isBaselineFile(path) {
+				candidates = append(candidates, path)
+			}
+			return nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("error listing baselines in %s: %v", baselineDirPath(), err)
+		}
+		return candidates, nil
+	}
+
+	var dirs []string
+	for _, target := range targets {
+		dir := filepath.Dir(target.BaselineFile())
+		if filepath.Base(dir) == imagesDirName {
+			dir = filepath.Dir(dir)
+		}
+		for _, d := range []string{dir, filepath.Join(dir, imagesDirName)} {
+			if !slices.Contains(dirs, d) {
+				dirs = append(dirs, d)
+			}
+		}
+	}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if entry.IsDir() || !isBaselineFile(path) {
+				continue
+			}
+			// Next to the scenes, only pngs with metadata are screenshot baselines, others are e.g. textures.
+			if filepath.Ext(path) == ".png" {
+				if _, err := os.Stat(lib.MetadataFile(path)); err != nil {
+					continue
+				}
+			}
+			candidates = append(candidates, path)
+		}
+	}
+	return candidates, nil
+}
+
+// baselineSceneExists reports whether the scene that a baseline was rendered from still exists. The baseline is mapped
+// back through the config's baseline locations and --baseline-dir, and the variant and locale suffixes of its name
+// (e.g. .empty.de) are stripped one by one until a scene is found.
+func baselineSceneExists(baseline string, config lib.Config) bool {
+	name, err := filepath.Abs(strings.TrimSuffix(baseline, filepath.Ext(baseline)))
+	if err != nil {
+		return true
+	}
+	// pngs are saved in the images dir of the directory they'd be in otherwise, see imageFile
+	if filepath.Base(filepath.Dir(name)) == imagesDirName {
+		name = filepath.Join(filepath.Dir(filepath.Dir(name)), filepath.Base(name))
+	}
+	exists := func(scene string) bool {
+		_, err := os.Stat(scene)
+		return err == nil
+	}
+
+	for scene, sceneConfig := range config.Scenes {
+		if sceneConfig.Baseline == "" {
+			continue
+		}
+		configured, err := filepath.Abs(ProjectPath + strings.TrimSuffix(sceneConfig.Baseline, filepath.Ext(sceneConfig.Baseline)))
+		if err != nil {
+			continue
+		}
+		if name == configured || strings.HasPrefix(name, configured+".") {
+			if exists(ProjectPath + scene) {
+				return true
+			}
+		}
+	}
+
+	scene := name
+	if BaselineDir != "" {
+		dir, err := filepath.Abs(baselineDirPath())
+		if err != nil {
+			return true
+		}
+		// baselines outside of --baseline-dir (e.g. matched by --baseline) are next to their scene
+		if rel, err := filepath.Rel(dir, name); err == nil && filepath.IsLocal(rel) {
+			if scene, err = filepath.Abs(filepath.Join(ProjectPath, rel)); err != nil {
+				return true
+			}
+		}
+	}
+	for {
+		if exists(scene + ".tscn") {
+			return true
+		}
+		i := strings.LastIndex(scene, ".")
+		if i <= strings.LastIndex(scene, string(filepath.Separator)) {
+			return false
+		}
+		scene = scene[:i]
+	}
+}
+
+// isBaselineFile reports whether a file is a video or screenshot baseline, rather than e.g. a noise map.
+func isBaselineFile(path string) bool {
+	switch filepath.Ext(path) {
+	case ".avi":
+		return true
+	case ".png":
+		return !strings.HasSuffix(path, ".noise.png")
+	}
+	return false
+}
+
+// print lists the scenes without baselines and the orphaned baselines, and with --verbose the matched pairs.
+func (m baselineMapping) print() {
+	if Verbose {
+		for _, target := range m.Matched {
+			fmt.Printf("  %s -> %s\n", target.Name(), target.BaselineFile())
+		}
+	}
+	fmt.Printf("Matched %d scenes with their baselines\n", len(m.Matched))
+	if len(m.Missing) > 0 {
+		fmt.Printf("❌ %d scenes without baselines:\n", len(m.Missing))
+		for _, target := range m.Missing {
+			fmt.Printf("  %s (expected %s)\n", target.Name(), target.BaselineFile())
+		}
+	}
+	if len(m.Orphans) > 0 {
+		fmt.Printf("⚠️ %d orphaned baselines without scenes:\n", len(m.Orphans))
+		for _, orphan := range m.Orphans {
+			fmt.Println("  " + orphan)
+		}
+	}
+}
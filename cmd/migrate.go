@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"godot-vrt/lib"
+)
+
+func init() {
+	RootCmd.AddCommand(migrateCmd)
+
+	migrateCmd.Flags().StringVarP(&ScenesGlob, "scenes", "s", "", "glob path to the .tscn files, relative from the godot project root (e.g. scenes-vrt/*.tscn)")
+	migrateCmd.MarkFlagRequired("scenes")
+
+	migrateCmd.Flags().StringVar(&BaselineDir, "baseline-dir", "", "directory to move the baselines into, mirroring the directory structure of the scenes (e.g. vrt-baselines/), relative from the project root")
+	migrateCmd.MarkFlagRequired("baseline-dir")
+
+	migrateCmd.Flags().StringVarP(&ProjectPath, "project", "p", ".", "path to the project root (only required if you run godot-vrt from a different directory)")
+	migrateCmd.Flags().BoolVar(&Screenshot, "screenshot", false, "move .png baselines instead of videos")
+	migrateCmd.Flags().StringVarP(&ConfigFile, "config", "c", "", "path to a config file with per-scene settings such as variants (defaults to godot-vrt.json in the project root if it exists)")
+	migrateCmd.Flags().StringSliceVar(&Locales, "locales", nil, "comma separated list of locales to render each scene in (e.g. en,de,ja)")
+}
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Moves baselines that are saved next to their scenes into --baseline-dir",
+	Run: func(cmd *cobra.Command, args []string) {
+		ProjectPath = lib.WithFolderSuffix(ProjectPath)
+
+		if err := migrateBaselines(); err != nil {
+			fmt.Println(err)
+			if !OmitExitCode {
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+// migrateBaselines moves the baselines next to the scenes, and the files that belong to them (such as metadata and
+// golden logs), to their location in --baseline-dir.
+func migrateBaselines() error {
+	sceneFiles, err := filepath.Glob(ProjectPath + ScenesGlob)
+	if err != nil {
+		return fmt.Errorf("failed to list files at %s: %v", ProjectPath+ScenesGlob, err)
+	}
+	if len(sceneFiles) == 0 {
+		return fmt.Errorf("search for files at %s yielded 0 results", ProjectPath+ScenesGlob)
+	}
+	if err := verifyLocales(); err != nil {
+		return err
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := createBaselineDir(); err != nil {
+		return err
+	}
+	moved := 0
+	for _, target := range sceneTargets(sceneFiles, config) {
+		from := target.withImagesDir(target.Name() + target.Ext())
+		to := target.BaselineFile()
+		if _, err := os.Stat(from); os.IsNotExist(err) {
+			continue
+		}
+		if _, err := os.Stat(to); err == nil {
+			return fmt.Errorf("can't move %s, because %s exists already", from, to)
+		}
+
+		fromFiles := append([]string{from}, baselineSidecars(from)...)
+		toFiles := append([]string{to}, baselineSidecars(to)...)
+		for i := range fromFiles {
+			if _, err := os.Stat(fromFiles[i]); os.IsNotExist(err) {
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(toFiles[i]), 0755); err != nil {
+				return fmt.Errorf("error creating dir: %v", err)
+			}
+			if err := os.Rename(fromFiles[i], toFiles[i]); err != nil {
+				return fmt.Errorf("error moving %s: %v", fromFiles[i], err)
+			}
+		}
+		fmt.Printf("Moved %s to %s\n", from, to)
+		moved++
+	}
+	fmt.Printf("Moved %d baselines into %s\n", moved, strings.TrimSuffix(baselineDirPath(), "/"))
+	return nil
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"godot-vrt/lib"
+)
+
+var ImportAssets bool
+var ImportCache string
+var Isolate bool
+var VirtualDisplay bool
+
+// renderProjectPath is the project that Godot renders from. It's a copy of ProjectPath in --isolate mode.
+var renderProjectPath string
+
+// renderEnv holds additional environment variables for Godot.
+var renderEnv []string
+
+// renderGodotArgs holds additional arguments for Godot.
+var renderGodotArgs []string
+
+// renderEnvironment describes the environment that scenes are rendered in.
+var renderEnvironment lib.Environment
+
+// virtualDisplay is the Xvfb instance that Godot renders on with --virtual-display.
+var virtualDisplay *lib.VirtualDisplay
+
+// startVirtualDisplay starts Xvfb with --virtual-display. The returned function stops it again, and is never nil.
+func startVirtualDisplay() (func(), error) {
+	if !VirtualDisplay {
+		return func() {}, nil
+	}
+	display, err := lib.StartVirtualDisplay(Verbose)
+	if err != nil {
+		return func() {}, err
+	}
+	virtualDisplay = display
+	fmt.Println("Started virtual display " + display.Display)
+	return display.Stop, nil
+}
+
+// prepareProject runs the steps that have to happen once per run before any scene is rendered.
+func prepareProject(ctx context.Context, tmpDir string) error {
+	renderProjectPath = ProjectPath
+	renderEnv = nil
+	renderGodotArgs = nil
+	if virtualDisplay != nil {
+		renderEnv = virtualDisplay.Env()
+		renderGodotArgs = lib.VirtualDisplayGodotArgs
+	}
+
+	if Isolate {
+		isolatedProject := tmpDir + "project/"
+		if err := lib.IsolateProject(ProjectPath, isolatedProject); err != nil {
+			return err
+		}
+		userData := tmpDir + "userdata/"
+		if err := os.MkdirAll(userData, 0755); err != nil {
+			return fmt.Errorf("error creating user data dir: %v", err)
+		}
+		renderProjectPath = isolatedProject
+		renderEnv = append(renderEnv, lib.UserDataEnv(userData)...)
+		fmt.Println("Rendering from isolated project " + isolatedProject)
+	}
+
+	var err error
+	renderEnvironment, err = lib.CurrentEnvironment(renderProjectPath, godotExecutableVersion, renderGodotArgs)
+	if err != nil {
+		return err
+	}
+	renderEnvironment.Encoder = lib.EncoderMovieWriter
+	if Batch {
+		renderEnvironment.Encoder = lib.EncoderBatch
+	}
+
+	if !ImportAssets && ImportCache == "" {
+		return nil
+	}
+
+	var hash string
+	if ImportCache != "" {
+		hash, err = lib.AssetsHash(renderProjectPath)
+		if err != nil {
+			return err
+		}
+		restored, err := lib.RestoreImportCache(ImportCache, hash, renderProjectPath)
+		if err != nil {
+			return err
+		}
+		if restored {
+			fmt.Println("Restored imported assets from cache " + hash)
+			return nil
+		}
+	}
+
+	fmt.Println("Importing assets")
+	if err := lib.ImportProject(ctx, GodotExecutable, renderProjectPath, renderEnv, Verbose); err != nil {
+		return err
+	}
+
+	// a dry run leaves the cache alone
+	if ImportCache != "" && !DryRun {
+		if err := lib.SaveImportCache(ImportCache, hash, renderProjectPath); err != nil {
+			return err
+		}
+		fmt.Println("Saved imported assets to cache " + hash)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"godot-vrt/lib"
+)
+
+var Apply bool
+var Retention time.Duration
+
+func init() {
+	RootCmd.AddCommand(pruneCmd)
+
+	pruneCmd.Flags().StringVarP(&ScenesGlob, "scenes", "s", "", "glob path to the .tscn files, relative from the godot project root (e.g. scenes-vrt/*.tscn)")
+	pruneCmd.MarkFlagRequired("scenes")
+
+	pruneCmd.Flags().StringVarP(&ProjectPath, "project", "p", ".", "path to the project root (only required if you run godot-vrt from a different directory)")
+	pruneCmd.Flags().StringVarP(&BaselineGlob, "baseline", "b", "", "glob path to the baseline files, relative from the godot project root; baselines matching it whose scene doesn't exist anymore are orphans (by default baselines are searched in --baseline-dir or next to the scenes)")
+	pruneCmd.Flags().StringVar(&BaselineDir, "baseline-dir", "", "directory the baselines are saved in, mirroring the directory structure of the scenes (e.g. vrt-baselines/), relative from the project root")
+	pruneCmd.Flags().BoolVar(&Screenshot, "screenshot", false, "look for .png baselines instead of videos")
+	pruneCmd.Flags().StringVarP(&ConfigFile, "config", "c", "", "path to a config file with per-scene settings such as variants (defaults to godot-vrt.json in the project root if it exists)")
+	pruneCmd.Flags().StringSliceVar(&Locales, "locales", nil, "comma separated list of locales to render each scene in (e.g. en,de,ja)")
+	pruneCmd.Flags().DurationVar(&Retention, "retention", 7*24*time.Hour, "delete files in vrt-results/ that are older than this, except for backups in vrt-results/replaced/ (0 keeps all results)")
+	pruneCmd.Flags().BoolVar(&Apply, "apply", false, "delete the listed files (without it, prune only lists them)")
+}
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Lists, and with --apply deletes, orphaned baselines, leftover temp directories and old results",
+	Long: `Lists, and with --apply deletes, baselines whose scene doesn't exist anymore (and their metadata, golden logs,
+noise maps and checkpoints), .vrt_ temp directories and .frames_ directories that interrupted runs left behind,
+and files in vrt-results/ that are older than --retention. Baselines that baseline --backup copied to
+vrt-results/replaced/ are kept.
+
+Don't run prune while other godot-vrt commands are running in the project, as it would delete their temp directories.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if Retention < 0 {
+			fmt.Println("Retention must not be negative")
+			os.Exit(1)
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		ProjectPath = lib.WithFolderSuffix(ProjectPath)
+
+		if err := prune(); err != nil {
+			fmt.Println(err)
+			if !OmitExitCode {
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+// pruneGroup is a kind of leftover files, e.g. orphaned baselines.
+type pruneGroup struct {
+	Title string
+	Paths []string
+}
+
+func prune() error {
+	groups, err := findPrunable()
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, group := range groups {
+		if len(group.Paths) == 0 {
+			continue
+		}
+		fmt.Printf("🗑️ %d %s:\n", len(group.Paths), group.Title)
+		for _, path := range group.Paths {
+			fmt.Println("  " + path)
+		}
+		count += len(group.Paths)
+	}
+	if count == 0 {
+		fmt.Println("Nothing to prune")
+		return nil
+	}
+	if !Apply {
+		fmt.Printf("Run prune with --apply to delete these %d files and directories\n", count)
+		return nil
+	}
+
+	for _, group := range groups {
+		for _, path := range group.Paths {
+			if err := os.RemoveAll(path); err != nil {
+				return fmt.Errorf("error deleting %s: %v", path, err)
+			}
+		}
+	}
+	fmt.Printf("Deleted %d files and directories\n", count)
+	return nil
+}
+
+// findPrunable lists the orphaned baselines with the files that belong to them, temp and frame directories, and
+// results older than --retention.
+func findPrunable() ([]pruneGroup, error) {
+	sceneFiles, err := filepath.Glob(ProjectPath + ScenesGlob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files at %s: %v", ProjectPath+ScenesGlob, err)
+	}
+	if len(sceneFiles) == 0 {
+		return nil, fmt.Errorf("search for files at %s yielded 0 results", ProjectPath+ScenesGlob)
+	}
+	if err := verifyLocales(); err != nil {
+		return nil, err
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	mapping, err := mapBaselines(sceneTargets(sceneFiles, config), config)
+	if err != nil {
+		return nil, err
+	}
+	var orphans []string
+	for _, orphan := range mapping.Orphans {
+		orphans = append(orphans, orphan)
+		for _, sidecar := range baselineSidecars(orphan) {
+			if _, err := os.Stat(sidecar); err == nil {
+				orphans = append(orphans, sidecar)
+			}
+		}
+	}
+
+	// InitTmpDir creates the temp dirs in the working directory, which usually is the project root.
+	var tmpDirs []string
+	for _, dir := range uniquePaths(".", ProjectPath) {
+		dirs, err := lib.FindTmpDirs(dir)
+		if err != nil {
+			return nil, err
+		}
+		tmpDirs = append(tmpDirs, dirs...)
+	}
+
+	// frames are extracted next to the videos, i.e. next to baselines and results
+	roots := []string{ProjectPath, "vrt-results"}
+	if BaselineDir != "" {
+		roots = append(roots, baselineDirPath())
+	}
+	var frameDirs []string
+	for _, root := range uniquePaths(roots...) {
+		if _, err := os.Stat(root); os.IsNotExist(err) {
+			continue
+		}
+		dirs, err := lib.FindFrameDirs(root)
+		if err != nil {
+			return nil, err
+		}
+		frameDirs = append(frameDirs, dirs...)
+	}
+	frameDirs = uniquePaths(frameDirs...)
+
+	var results []string
+	if Retention > 0 {
+		results, err = lib.FindStaleFiles("vrt-results", time.Now().Add(-Retention))
+		if err != nil {
+			return nil, err
+		}
+		// files in frame dirs are deleted with their dir already, and backups of replaced baselines are kept
+		results = withoutPathsInside(results, append(frameDirs, backupDir))
+	}
+
+	return []pruneGroup{
+		{Title: "orphaned baselines and their files", Paths: orphans},
+		{Title: "leftover temp directories", Paths: tmpDirs},
+		{Title: "leftover frame directories", Paths: frameDirs},
+		{Title: fmt.Sprintf("results older than %v", Retention), Paths: results},
+	}, nil
+}
+
+// uniquePaths removes paths that point to the same location as an earlier path.
+func uniquePaths(paths ...string) []string {
+	var unique []string
+	seen := map[string]bool{}
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			abs = path
+		}
+		if !seen[abs] {
+			seen[abs] = true
+			unique = append(unique, path)
+		}
+	}
+	return unique
+}
+
+// withoutPathsInside removes the paths that are inside any of the dirs.
+func withoutPathsInside(paths, dirs []string) []string {
+	var kept []string
+	for _, path := range paths {
+		inside := false
+		absPath, _ := filepath.Abs(path)
+		for _, dir := range dirs {
+			absDir, _ := filepath.Abs(dir)
+			if rel, err := filepath.Rel(absDir, absPath); err == nil && filepath.IsLocal(rel) {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			kept = append(kept, path)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// setupPruneProject creates the files in a new project dir, and points the flags at it.
+func setupPruneProject(t *testing.T, files ...string) string {
+	project := t.TempDir()
+	for _, file := range files {
+		path := filepath.Join(project, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	projectPath, baselineDir, baselineGlob, locales, retention := ProjectPath, BaselineDir, BaselineGlob, Locales, Retention
+	t.Cleanup(func() {
+		ProjectPath, BaselineDir, BaselineGlob, Locales, Retention = projectPath, baselineDir, baselineGlob, locales, retention
+	})
+	ProjectPath = project + "/"
+	BaselineDir, BaselineGlob, Locales, Retention = "", "", nil, 0
+	return project
+}
+
+// prunedOrphans returns the orphaned baselines and their files that prune would delete, relative from the project.
+func prunedOrphans(t *testing.T, project string) []string {
+	groups, err := findPrunable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var orphans []string
+	for _, path := range groups[0].Paths {
+		rel, err := filepath.Rel(project, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		orphans = append(orphans, filepath.ToSlash(rel))
+	}
+	return orphans
+}
+
+func TestPruneKeepsBaselinesOfOtherLocales(t *testing.T) {
+	project := setupPruneProject(t,
+		"vrt/menu.tscn", "vrt/menu.avi", "vrt/menu.de.avi", "vrt/menu.de.vrt.json",
+		"vrt/deleted.avi", "vrt/deleted.vrt.json", "vrt/deleted.ja.avi",
+	)
+	ScenesGlob = "vrt/*.tscn"
+
+	want := []string{"vrt/deleted.avi", "vrt/deleted.vrt.json", "vrt/deleted.ja.avi"}
+	if got := prunedOrphans(t, project); !slices.Equal(got, want) {
+		t.Errorf("orphans = %v, want %v", got, want)
+	}
+}
+
+func TestPruneKeepsBaselinesOfScenesOutsideTheGlob(t *testing.T) {
+	project := setupPruneProject(t,
+		"vrt/menu.tscn", "vrt/hud.tscn",
+		"baselines/vrt/menu.avi", "baselines/vrt/hud.png", "baselines/vrt/hud.vrt.json",
+		"baselines/vrt/old.avi",
+	)
+	ScenesGlob = "vrt/menu.tscn"
+	BaselineDir = "baselines"
+
+	want := []string{"baselines/vrt/old.avi"}
+	if got := prunedOrphans(t, project); !slices.Equal(got, want) {
+		t.Errorf("orphans = %v, want %v", got, want)
+	}
+}
+
+func TestPruneFindsPngsInTheImagesDir(t *testing.T) {
+	project := setupPruneProject(t,
+		"vrt/menu.tscn", "vrt/vrt-images/menu.png", "vrt/vrt-images/menu.vrt.json",
+		"vrt/vrt-images/deleted.png", "vrt/vrt-images/deleted.vrt.json",
+	)
+	ScenesGlob = "vrt/*.tscn"
+	screenshot := Screenshot
+	t.Cleanup(func() { Screenshot = screenshot })
+	Screenshot = true
+
+	want := []string{"vrt/vrt-images/deleted.png", "vrt/vrt-images/deleted.vrt.json"}
+	if got := prunedOrphans(t, project); !slices.Equal(got, want) {
+		t.Errorf("orphans = %v, want %v", got, want)
+	}
+}
+
+func TestPruneDeletesNoiseMapsOfOrphanedVideos(t *testing.T) {
+	project := setupPruneProject(t,
+		"vrt/menu.tscn", "vrt/menu.avi", "vrt/vrt-images/menu.noise.png",
+		"vrt/deleted.avi", "vrt/vrt-images/deleted.noise.png",
+	)
+	ScenesGlob = "vrt/*.tscn"
+
+	want := []string{"vrt/deleted.avi", "vrt/vrt-images/deleted.noise.png"}
+	if got := prunedOrphans(t, project); !slices.Equal(got, want) {
+		t.Errorf("orphans = %v, want %v", got, want)
+	}
+}
+
+func TestPruneKeepsBackups(t *testing.T) {
+	project := setupPruneProject(t, "vrt/menu.tscn")
+	ScenesGlob = "vrt/*.tscn"
+	Retention = time.Hour
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(project); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	lastWeek := time.Now().Add(-7 * 24 * time.Hour)
+	for _, file := range []string{"vrt-results/vrt/old.avi", backupDir + "vrt/menu.avi"} {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, lastWeek, lastWeek); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	groups, err := findPrunable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := groups[3].Paths, []string{"vrt-results/vrt/old.avi"}; !slices.Equal(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+}
@@ -1,10 +1,17 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"godot-vrt/lib"
 )
 
 var GodotExecutable string
@@ -13,6 +20,18 @@ var ScenesGlob string
 var ProjectPath string
 var Frames int
 var OmitExitCode bool
+var ConfigFile string
+var Locales []string
+var RenderTimeout time.Duration
+var Batch bool
+var Checkpoints bool
+var Screenshot bool
+var WarmupFrames int
+var AllowUnsupportedGodot bool
+var BaselineDir string
+
+// godotExecutableVersion is the version of GodotExecutable once it has been validated.
+var godotExecutableVersion lib.GodotVersion
 
 func init() {
 	RootCmd.PersistentFlags().BoolVarP(&Verbose, "verbose", "v", false, "verbose output")
@@ -28,11 +47,83 @@ var RootCmd = &cobra.Command{
 	},
 }
 
+// exitInterrupted is the exit code after SIGINT or SIGTERM (128 + SIGINT, like shells use).
+const exitInterrupted = 130
+
+// cleanups run before godot-vrt exits through exit, because os.Exit skips deferred calls.
+var cleanups []func()
+
+// onExit registers a cleanup that exit runs, and returns it wrapped so that it only runs once. Defer the returned
+// func as well, so that the cleanup also runs when the command returns normally.
+func onExit(cleanup func()) func() {
+	once := sync.OnceFunc(cleanup)
+	cleanups = append(cleanups, once)
+	return once
+}
+
+// injectedFiles holds the restores of files that renders inject into the project, such as override.cfg. Unlike the
+// cleanups, a second interrupt runs them too, so that quitting immediately doesn't leave the project modified.
+var injectedFiles struct {
+	sync.Mutex
+	restores []func()
+}
+
+// restoreOnInterrupt registers the restore of an injected file, and returns it wrapped so that it only runs once.
+// Defer the returned func, so that the file is also restored when the render returns normally.
+func restoreOnInterrupt(restore func()) func() {
+	once := sync.OnceFunc(restore)
+	injectedFiles.Lock()
+	defer injectedFiles.Unlock()
+	injectedFiles.restores = append(injectedFiles.restores, once)
+	return once
+}
+
+// restoreInjectedFiles runs the registered restores, the latest first.
+func restoreInjectedFiles() {
+	injectedFiles.Lock()
+	restores := injectedFiles.restores
+	injectedFiles.restores = nil
+	injectedFiles.Unlock()
+	for i := len(restores) - 1; i >= 0; i-- {
+		restores[i]()
+	}
+}
+
+// exit runs the registered cleanups and exits with code, or with exitInterrupted if ctx was cancelled by a signal.
+func exit(ctx context.Context, code int) {
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		cleanups[i]()
+	}
+	if ctx.Err() != nil {
+		code = exitInterrupted
+	}
+	os.Exit(code)
+}
+
 func Execute() {
-	if err := RootCmd.Execute(); err != nil {
+	// SIGINT and SIGTERM cancel the context, which kills Godot and ffmpeg, so that the commands can clean up and return.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		// a second signal terminates godot-vrt right away, after restoring the files injected into the project
+		again := make(chan os.Signal, 1)
+		signal.Notify(again, os.Interrupt, syscall.SIGTERM)
+		stop()
+		fmt.Println("Interrupted, cleaning up (interrupt again to quit immediately)")
+		<-again
+		restoreInjectedFiles()
+		os.Exit(exitInterrupted)
+	}()
+	lib.CancelCommandsWith(ctx)
+
+	err := RootCmd.ExecuteContext(ctx)
+	if err != nil {
 		fmt.Println(err)
-		if !OmitExitCode {
-			os.Exit(1)
-		}
+	}
+	if ctx.Err() != nil {
+		exit(ctx, exitInterrupted)
+	}
+	if err != nil && !OmitExitCode {
+		os.Exit(1)
 	}
 }
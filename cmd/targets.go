@@ -0,0 +1,444 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"godot-vrt/lib"
+)
+
+const defaultConfigFile = "godot-vrt.json"
+const quarantineFile = "vrt-quarantine.txt"
+
+// sceneTarget is a single render of a scene. Scenes without variants have exactly one target.
+type sceneTarget struct {
+	// SceneFile is the scene's path as returned by the scenes glob (i.e. including the project path).
+	SceneFile string
+	Variant   *lib.Variant
+	// Locale is empty if the scene is rendered with the project's default locale.
+	Locale string
+	// Screenshot targets render a single png after WarmupFrames frames instead of a video.
+	Screenshot   bool
+	WarmupFrames int
+	// CompareFrames selects the frames of a video that are compared against the baseline.
+	CompareFrames lib.FrameSelection
+	// ConfiguredBaseline is the baseline location from the config file, relative from the project root. It's empty
+	// if the baseline location is derived from the scene.
+	ConfiguredBaseline string
+}
+
+// Ext returns the file extension of the target's renders and baseline.
+func (t sceneTarget) Ext() string {
+	if t.Screenshot {
+		return ".png"
+	}
+	return ".avi"
+}
+
+// Frames returns how many frames Godot has to render for the target.
+func (t sceneTarget) Frames() int {
+	if t.Screenshot {
+		return t.WarmupFrames + 1
+	}
+	return Frames
+}
+
+// Name identifies the target without file extension, e.g. vrt/health_bar, vrt/health_bar.empty for a variant,
+// or vrt/health_bar.empty.de for a variant in a specific locale.
+func (t sceneTarget) Name() string {
+	return strings.TrimSuffix(t.SceneFile, ".tscn") + t.suffix()
+}
+
+// suffix identifies the variant and locale of the target, e.g. .empty.de
+func (t sceneTarget) suffix() string {
+	suffix := ""
+	if t.Variant != nil {
+		suffix += "." + t.Variant.Name
+	}
+	if t.Locale != "" {
+		suffix += "." + t.Locale
+	}
+	return suffix
+}
+
+// BaselineFile returns the path of the target's baseline. Baselines are saved next to their scene, or with
+// --baseline-dir in a directory tree that mirrors the scenes, e.g. vrt-baselines/vrt/health_bar.avi. The config file
+// can override the location of individual scenes. Screenshot baselines outside of --baseline-dir are saved in an
+// images dir (see imageFile).
+func (t sceneTarget) BaselineFile() string {
+	if t.ConfiguredBaseline != "" {
+		return t.withImagesDir(ProjectPath + strings.TrimSuffix(t.ConfiguredBaseline, filepath.Ext(t.ConfiguredBaseline)) + t.suffix() + t.Ext())
+	}
+	if BaselineDir == "" {
+		return t.withImagesDir(t.Name() + t.Ext())
+	}
+	return baselineDirPath() + t.NameFromProjectRoot() + t.Ext()
+}
+
+func (t sceneTarget) withImagesDir(baselineFile string) string {
+	if t.Screenshot {
+		return imageFile(baselineFile)
+	}
+	return baselineFile
+}
+
+// NameFromProjectRoot is like Name, but relative from the project root instead of the working directory.
+func (t sceneTarget) NameFromProjectRoot() string {
+	return strings.Replace(t.Name(), ProjectPath, "", 1)
+}
+
+func (t sceneTarget) SceneFileFromProjectRoot() string {
+	return strings.Replace(t.SceneFile, ProjectPath, "", 1)
+}
+
+func verifyLocales() error {
+	for _, locale := range Locales {
+		if err := lib.VerifyLocale(locale); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadConfig() (lib.Config, error) {
+	if ConfigFile != "" {
+		return lib.LoadConfig(ConfigFile)
+	}
+	// The default config file is optional.
+	if _, err := os.Stat(ProjectPath + defaultConfigFile); err != nil {
+		return lib.Config{}, nil
+	}
+	return lib.LoadConfig(ProjectPath + defaultConfigFile)
+}
+
+// loadQuarantine returns the quarantined scenes of the config and of the optional vrt-quarantine.txt in the project root.
+func loadQuarantine(config lib.Config) ([]lib.Quarantine, error) {
+	quarantine := config.Quarantine
+	if _, err := os.Stat(ProjectPath + quarantineFile); err != nil {
+		return quarantine, nil
+	}
+	fromFile, err := lib.LoadQuarantineFile(ProjectPath + quarantineFile)
+	if err != nil {
+		return nil, err
+	}
+	return append(quarantine, fromFile...), nil
+}
+
+func sceneTargets(sceneFiles []string, config lib.Config) []sceneTarget {
+	locales := Locales
+	if len(locales) == 0 {
+		locales = []string{""}
+	}
+
+	var targets []sceneTarget
+	for _, file := range sceneFiles {
+		for _, locale := range locales {
+			t := sceneTarget{SceneFile: file, Locale: locale}
+			sceneConfig := config.Scene(t.SceneFileFromProjectRoot())
+			t.Screenshot = Screenshot || sceneConfig.Screenshot
+			t.WarmupFrames = max(WarmupFrames, sceneConfig.WarmupFrames)
+			t.CompareFrames = lib.FrameSelection{
+				Skip:   max(SkipFrames, sceneConfig.SkipFrames),
+				Ranges: sceneConfig.CompareFrames,
+			}
+			t.ConfiguredBaseline = sceneConfig.Baseline
+
+			variants := sceneConfig.Variants
+			if len(variants) == 0 {
+				targets = append(targets, t)
+				continue
+			}
+			for i := range variants {
+				t.Variant = &variants[i]
+				targets = append(targets, t)
+			}
+		}
+	}
+	return targets
+}
+
+// targetRender is the outcome of rendering a single target.
+type targetRender struct {
+	Result lib.RenderResult
+	Err    error
+}
+
+// renderTargets renders all targets into the files returned by outputFile. Without --batch, each target is rendered
+// by its own Godot process. With --batch, a single Godot process renders all targets of a locale.
+func renderTargets(ctx context.Context, targets []sceneTarget, tmpDir string, outputFile func(sceneTarget) string) ([]targetRender, error) {
+	renders := make([]targetRender, len(targets))
+	if !Batch {
+		for i, target := range targets {
+			// don't start the remaining renders after an interrupt
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			renders[i].Result, renders[i].Err = renderTarget(ctx, target, outputFile(target))
+		}
+		return renders, nil
+	}
+
+	// The locale is forced for the whole Godot process, so we need one batch per locale.
+	var locales []string
+	for _, target := range targets {
+		if !slices.Contains(locales, target.Locale) {
+			locales = append(locales, target.Locale)
+		}
+	}
+	for batchIndex, locale := range locales {
+		var indexes []int
+		for i, target := range targets {
+			if target.Locale == locale {
+				indexes = append(indexes, i)
+			}
+		}
+		results, err := renderBatch(ctx, targets, indexes, fmt.Sprintf("%sbatch_%d/", tmpDir, batchIndex), outputFile)
+		if err != nil {
+			return nil, err
+		}
+		for i, result := range results {
+			renders[indexes[i]] = targetRender{Result: result.Result, Err: result.Err}
+		}
+	}
+	return renders, nil
+}
+
+func renderBatch(ctx context.Context, targets []sceneTarget, indexes []int, batchDir string, outputFile func(sceneTarget) string) ([]lib.BatchResult, error) {
+	var scenes []lib.BatchScene
+	for _, i := range indexes {
+		scene, cleanup, err := targetScene(targets[i])
+		if err != nil {
+			return nil, err
+		}
+		defer restoreOnInterrupt(cleanup)()
+		s := lib.BatchScene{
+			Name:                     targets[i].Name(),
+			SceneFileFromProjectRoot: scene,
+			OutputFile:               outputFile(targets[i]),
+			Frames:                   targets[i].Frames(),
+		}
+		if Checkpoints {
+			s.CheckpointsDir = checkpointsDir(s.OutputFile)
+			if err := clearDir(s.CheckpointsDir); err != nil {
+				return nil, err
+			}
+		}
+		scenes = append(scenes, s)
+	}
+	if Checkpoints {
+		remove, err := lib.InjectCheckpointAutoload(renderProjectPath)
+		if err != nil {
+			return nil, err
+		}
+		defer restoreOnInterrupt(remove)()
+	}
+	if locale := targets[indexes[0]].Locale; locale != "" {
+		restore, err := lib.InjectLocale(renderProjectPath, locale)
+		if err != nil {
+			return nil, err
+		}
+		defer restoreOnInterrupt(restore)()
+	}
+
+	// The timeout applies per scene, so the whole batch gets the sum of them.
+	if RenderTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, RenderTimeout*time.Duration(len(scenes)))
+		defer cancel()
+	}
+
+	return lib.RenderBatch(ctx, lib.RenderBatchArgs{
+		Scenes:      scenes,
+		GodotBinary: GodotExecutable,
+		Verbose:     Verbose,
+		Frames:      Frames,
+		ProjectPath: renderProjectPath,
+		Env:         renderEnv,
+		GodotArgs:   renderGodotArgs,
+		TmpDir:      batchDir,
+	})
+}
+
+// targetScene returns the scene that Godot has to render for a target, which is a generated wrapper scene for variants.
+func targetScene(target sceneTarget) (string, func(), error) {
+	scene := target.SceneFileFromProjectRoot()
+	if target.Variant == nil {
+		return scene, func() {}, nil
+	}
+	return lib.GenerateVariantScene(renderProjectPath, scene, *target.Variant)
+}
+
+func renderTarget(ctx context.Context, target sceneTarget, outputFile string) (lib.RenderResult, error) {
+	scene, cleanup, err := targetScene(target)
+	if err != nil {
+		return lib.RenderResult{}, err
+	}
+	defer restoreOnInterrupt(cleanup)()
+	if target.Locale != "" {
+		restore, err := lib.InjectLocale(renderProjectPath, target.Locale)
+		if err != nil {
+			return lib.RenderResult{}, err
+		}
+		defer restoreOnInterrupt(restore)()
+	}
+	var userArgs []string
+	if Checkpoints {
+		dir := checkpointsDir(outputFile)
+		if err := clearDir(dir); err != nil {
+			return lib.RenderResult{}, err
+		}
+		remove, err := lib.InjectCheckpointAutoload(renderProjectPath)
+		if err != nil {
+			return lib.RenderResult{}, err
+		}
+		defer restoreOnInterrupt(remove)()
+		userArgs = append(userArgs, lib.CheckpointsArg(dir))
+	}
+
+	if RenderTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, RenderTimeout)
+		defer cancel()
+	}
+
+	return lib.RenderScene(ctx, lib.RenderSceneArgs{
+		SceneFileFromProjectRoot: scene,
+		OutputFile:               outputFile,
+		GodotBinary:              GodotExecutable,
+		Verbose:                  Verbose,
+		Frames:                   target.Frames(),
+		ProjectPath:              renderProjectPath,
+		Env:                      renderEnv,
+		GodotArgs:                renderGodotArgs,
+		UserArgs:                 userArgs,
+	})
+}
+
+// checkpointsDir returns the directory for the checkpoint screenshots of a render, e.g.
+// vrt/vrt-images/door.checkpoints/ for vrt/door.avi (see imageFile).
+func checkpointsDir(outputFile string) string {
+	return imageFile(strings.TrimSuffix(outputFile, filepath.Ext(outputFile))+".checkpoints") + "/"
+}
+
+// imagesDirName is the directory next to the scenes that holds the pngs of their baselines, such as screenshots,
+// noise maps and checkpoints. It contains a .gdignore file, so that the Godot editor doesn't import them.
+const imagesDirName = "vrt-images"
+
+// imageFile returns where a png (or a directory of pngs) that belongs to a baseline is saved. Godot ignores
+// --baseline-dir already, everywhere else the file is saved in the images dir next to it, e.g. vrt/vrt-images/menu.png
+// instead of vrt/menu.png.
+func imageFile(path string) string {
+	dir := filepath.Dir(path)
+	if filepath.Base(dir) == imagesDirName || inBaselineDir(path) {
+		return path
+	}
+	return filepath.Join(dir, imagesDirName, filepath.Base(path))
+}
+
+// inBaselineDir reports whether path is inside of --baseline-dir.
+func inBaselineDir(path string) bool {
+	if BaselineDir == "" {
+		return false
+	}
+	dir, err := filepath.Abs(baselineDirPath())
+	if err != nil {
+		return false
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(dir, abs)
+	return err == nil && filepath.IsLocal(rel)
+}
+
+// createImagesDir creates the images dir of a baseline with a .gdignore file, if the target saves pngs there.
+func createImagesDir(target sceneTarget) error {
+	if !target.Screenshot && !Checkpoints && Calibrate < 2 {
+		return nil
+	}
+	dir := filepath.Dir(noiseMapFile(target.BaselineFile()))
+	if filepath.Base(dir) != imagesDirName {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating %s: %v", dir, err)
+	}
+	gdignore := filepath.Join(dir, ".gdignore")
+	if _, err := os.Stat(gdignore); os.IsNotExist(err) {
+		if err := os.WriteFile(gdignore, nil, 0644); err != nil {
+			return fmt.Errorf("error creating %s: %v", gdignore, err)
+		}
+	}
+	return nil
+}
+
+// baselineDirPath returns --baseline-dir with a trailing slash. Relative paths are relative from the project root.
+func baselineDirPath() string {
+	if filepath.IsAbs(BaselineDir) {
+		return lib.WithFolderSuffix(BaselineDir)
+	}
+	return lib.WithFolderSuffix(filepath.Join(ProjectPath, BaselineDir))
+}
+
+// createBaselineDir creates --baseline-dir with a .gdignore file, so that Godot doesn't import the baselines.
+func createBaselineDir() error {
+	if BaselineDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(baselineDirPath(), 0755); err != nil {
+		return fmt.Errorf("error creating baseline dir: %v", err)
+	}
+	gdignore := baselineDirPath() + ".gdignore"
+	if _, err := os.Stat(gdignore); os.IsNotExist(err) {
+		if err := os.WriteFile(gdignore, nil, 0644); err != nil {
+			return fmt.Errorf("error creating %s: %v", gdignore, err)
+		}
+	}
+	return nil
+}
+
+// baselineSidecars returns the files that belong to a baseline, such as its metadata, whether they exist or not.
+func baselineSidecars(baselineFile string) []string {
+	return []string{
+		lib.MetadataFile(baselineFile),
+		goldenLogFile(baselineFile),
+		noiseMapFile(baselineFile),
+		strings.TrimSuffix(checkpointsDir(baselineFile), "/"),
+	}
+}
+
+// goldenLogFile returns the golden log of a baseline, e.g. vrt/menu.log.golden for vrt/menu.avi.
+func goldenLogFile(baselineFile string) string {
+	return strings.TrimSuffix(baselineFile, filepath.Ext(baselineFile)) + ".log.golden"
+}
+
+// noiseMapFile returns the calibrated noise map of a baseline, e.g. vrt/vrt-images/particles.noise.png for
+// vrt/particles.avi (see imageFile).
+func noiseMapFile(baselineFile string) string {
+	return imageFile(strings.TrimSuffix(baselineFile, filepath.Ext(baselineFile)) + ".noise.png")
+}
+
+// clearDir makes sure that dir exists and is empty, so that no files from earlier runs are left over.
+func clearDir(dir string) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("error clearing %s: %v", dir, err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating %s: %v", dir, err)
+	}
+	return nil
+}
+
+// rendersDiffer compares two renders of a target. Videos are compared through a diff video at diffOutFile.
+func rendersDiffer(target sceneTarget, rendered, baseline, diffOutFile string) (bool, error) {
+	if target.Screenshot {
+		return lib.ImagesDiffer(rendered, baseline)
+	}
+	return lib.HasDiff(rendered, baseline, diffOutFile, Verbose, Frames, target.CompareFrames)
+}
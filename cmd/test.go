@@ -1,11 +1,14 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"slices"
 	"strings"
+	"time"
 
 	"godot-vrt/lib"
 
@@ -14,6 +17,11 @@ import (
 
 var BaselineGlob string
 var RetainAssets bool
+var FailOnScriptErrors bool
+var SkipFrames int
+var Retries int
+var RetryPolicy string
+var StrictEnvironment bool
 
 func init() {
 	RootCmd.AddCommand(testCmd)
@@ -24,11 +32,28 @@ func init() {
 	testCmd.Flags().StringVarP(&ScenesGlob, "scenes", "s", "", "glob path to the .tscn files, relative from the godot project root (e.g. scenes-vrt/*.tscn)")
 	testCmd.MarkFlagRequired("scenes")
 
-	testCmd.Flags().StringVarP(&BaselineGlob, "baseline", "b", "", "glob path to the baseline .avi files, relative from the godot project root (e.g. scenes-vrt/*.avi)")
-	testCmd.MarkFlagRequired("baseline")
+	testCmd.Flags().StringVarP(&BaselineGlob, "baseline", "b", "", "glob path to the baseline .avi files, relative from the godot project root (e.g. scenes-vrt/*.avi); optional, baselines are looked up next to their scenes or in --baseline-dir, and files matching the glob without a scene are reported as orphans")
+	testCmd.Flags().StringVar(&BaselineDir, "baseline-dir", "", "directory the baselines are saved in, mirroring the directory structure of the scenes (e.g. vrt-baselines/), relative from the project root")
 
 	testCmd.Flags().StringVarP(&ProjectPath, "project", "p", ".", "path to the project root (only required if you run godot-vrt from a different directory)")
 	testCmd.Flags().IntVarP(&Frames, "frames", "f", 60, "number of frames to render")
+	testCmd.Flags().IntVar(&SkipFrames, "skip-frames", 0, "number of frames at the start of each video that are ignored when comparing (the full video is still recorded)")
+	testCmd.Flags().BoolVar(&Screenshot, "screenshot", false, "render a single frame and save it as a .png instead of a video (useful for static scenes)")
+	testCmd.Flags().IntVar(&WarmupFrames, "warmup-frames", 0, "number of frames to render before taking the screenshot in --screenshot mode")
+	testCmd.Flags().StringVarP(&ConfigFile, "config", "c", "", "path to a config file with per-scene settings such as variants (defaults to godot-vrt.json in the project root if it exists)")
+	testCmd.Flags().StringSliceVar(&Locales, "locales", nil, "comma separated list of locales to render each scene in (e.g. en,de,ja)")
+	testCmd.Flags().DurationVar(&RenderTimeout, "timeout", 5*time.Minute, "maximum time to render a single scene before godot gets killed (0 disables the timeout)")
+	testCmd.Flags().BoolVar(&ImportAssets, "import", false, "let godot import the project's assets once before rendering (useful for fresh checkouts without a .godot directory)")
+	testCmd.Flags().StringVar(&ImportCache, "import-cache", "", "directory to cache the imported .godot directory in, keyed by a hash of the project's assets (implies --import)")
+	testCmd.Flags().BoolVar(&Isolate, "isolate", false, "render from a copy of the project with a throwaway user:// directory, so that renders can't modify the project or leak saved state")
+	testCmd.Flags().BoolVar(&AllowUnsupportedGodot, "allow-unsupported-godot", false, "use Godot versions that aren't supported, such as custom engine builds and release candidates")
+	testCmd.Flags().BoolVar(&VirtualDisplay, "virtual-display", false, "start a virtual X display (Xvfb) and render on it with Godot's Compatibility renderer and Mesa's software rasterizer (for CI machines without a display or GPU)")
+	testCmd.Flags().BoolVar(&Batch, "batch", false, "render all scenes in a single godot process (one per locale) instead of starting godot for every scene")
+	testCmd.Flags().BoolVar(&Checkpoints, "checkpoints", false, "inject the VRT autoload so that scenes can save named screenshots with VRT.checkpoint(\"name\"), which are compared against <scene>.checkpoints/<name>.png")
+	testCmd.Flags().BoolVar(&FailOnScriptErrors, "fail-on-script-errors", false, "fail a scene if godot prints errors while rendering it, even if there is no visual difference")
+	testCmd.Flags().IntVar(&Retries, "retries", 0, "render scenes that differ from their baseline or time out this many more times, and let them pass if the retries match (see --retry-policy)")
+	testCmd.Flags().StringVar(&RetryPolicy, "retry-policy", lib.RetryPolicyAny, "how many retries of a failed scene must match its baseline for it to pass: \"any\" or \"majority\"")
+	testCmd.Flags().BoolVar(&StrictEnvironment, "strict-environment", false, "fail scenes whose baseline was rendered in a different environment (Godot version, renderer, resolution, frames or OS) instead of only warning")
 	testCmd.Flags().BoolVar(&RetainAssets, "retain-assets", false, "will keep the test videos around if set to true (useful for debugging why a test didn't fail)")
 }
 
@@ -36,28 +61,82 @@ var testCmd = &cobra.Command{
 	Use:   "test",
 	Short: "Runs visual regression testing by rendering scenes and comparing them to their baselines",
 	//Long:  `Test long description`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if WarmupFrames < 0 {
+			fmt.Println("Warmup frames must not be negative")
+			os.Exit(1)
+		}
+		if SkipFrames < 0 {
+			fmt.Println("Skip frames must not be negative")
+			os.Exit(1)
+		}
+		if Retries < 0 {
+			fmt.Println("Retries must not be negative")
+			os.Exit(1)
+		}
+		if _, err := lib.RetriesPass(RetryPolicy, 0, 0); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return nil
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		ProjectPath = lib.WithFolderSuffix(ProjectPath)
 
-		if err := lib.Validate(GodotExecutable, ProjectPath); err != nil {
+		stopDisplay, err := startVirtualDisplay()
+		stopDisplay = onExit(stopDisplay)
+		if err != nil {
 			fmt.Println(err)
 			if !OmitExitCode {
-				os.Exit(1)
+				exit(cmd.Context(), 1)
 			}
 		}
+		defer stopDisplay()
 
-		hasFailures, err := testScenes()
+		godotExecutableVersion, err = lib.Validate(GodotExecutable, ProjectPath, AllowUnsupportedGodot, virtualDisplay)
 		if err != nil {
 			fmt.Println(err)
 			if !OmitExitCode {
-				os.Exit(1)
+				exit(cmd.Context(), 1)
+			}
+		}
+
+		report, err := testScenes(cmd.Context())
+		if err != nil {
+			fmt.Println(err)
+			if !OmitExitCode {
+				exit(cmd.Context(), 1)
+			}
+		}
+		if len(report.FlakyPassed) > 0 {
+			fmt.Printf("🎲 Scenes passed on retry: %v\n", report.FlakyPassed)
+		}
+		if len(report.TimedOut) > 0 {
+			fmt.Printf("⏱️ Scenes timed out: %v\n", report.TimedOut)
+		}
+		if len(report.ScriptErrors) > 0 {
+			fmt.Printf("📜 Scenes printed errors: %v\n", report.ScriptErrors)
+		}
+		if len(report.LogChanged) > 0 {
+			fmt.Printf("📜 Scenes printed a different log: %v\n", report.LogChanged)
+		}
+		if len(report.EnvironmentChanged) > 0 {
+			fmt.Printf("🖥️ Scenes rendered in a different environment than their baseline: %v\n", report.EnvironmentChanged)
+		}
+		if len(report.CheckpointsChanged) > 0 {
+			fmt.Printf("📸 Scenes with different checkpoints: %v\n", report.CheckpointsChanged)
+		}
+		if len(report.Quarantined) > 0 {
+			fmt.Println("🚧 Quarantined scenes (their failures don't fail the test):")
+			for _, q := range report.Quarantined {
+				fmt.Printf("  %s: %s, quarantined since %s: %s\n", q.Name, q.Status, q.Since, q.Reason)
 			}
 		}
-		if hasFailures {
+		if report.hasFailures() {
 			fmt.Println("❌ One or more tests failed")
 			// todo: document error codes (available range is 1-127: we use 1 for generic errors, and 50 for test failures)
 			if !OmitExitCode {
-				os.Exit(50)
+				exit(cmd.Context(), 50)
 			}
 			return
 		}
@@ -65,76 +144,397 @@ var testCmd = &cobra.Command{
 	},
 }
 
-func testScenes() (bool, error) {
+// testReport lists the scenes (or rather targets) that didn't pass.
+type testReport struct {
+	Failed   []string
+	TimedOut []string
+	// ScriptErrors lists scenes that printed errors while rendering (only with --fail-on-script-errors).
+	ScriptErrors []string
+	// LogChanged lists scenes whose console output differs from their golden log.
+	LogChanged []string
+	// CheckpointsChanged lists scenes with checkpoint screenshots that differ from their baselines.
+	CheckpointsChanged []string
+	// EnvironmentChanged lists scenes whose baseline was rendered in a different environment (only with
+	// --strict-environment).
+	EnvironmentChanged []string
+	// FlakyPassed lists scenes that failed at first, but passed on retry. They don't count as failures.
+	FlakyPassed []string
+	// Quarantined lists quarantined scenes, which aren't in any of the other lists.
+	Quarantined []quarantinedScene
+}
+
+type quarantinedScene struct {
+	lib.Quarantine
+	Name   string
+	Status string
+}
+
+func (r testReport) hasFailures() bool {
+	return len(r.Failed) > 0 || len(r.TimedOut) > 0 || len(r.ScriptErrors) > 0 || len(r.LogChanged) > 0 || len(r.CheckpointsChanged) > 0 ||
+		len(r.EnvironmentChanged) > 0
+}
+
+func testScenes(ctx context.Context) (testReport, error) {
+	var report testReport
+
 	// list all sceneFiles at config.Scenes (that's a glob)
 	sceneFiles, err := filepath.Glob(ProjectPath + ScenesGlob)
 	if err != nil {
-		return false, fmt.Errorf("failed to list files at %s: %v", ProjectPath+ScenesGlob, err)
+		return report, fmt.Errorf("failed to list files at %s: %v", ProjectPath+ScenesGlob, err)
 	}
 	if len(sceneFiles) == 0 {
-		return false, fmt.Errorf("search for files at %s yielded 0 results", ProjectPath+ScenesGlob)
+		return report, fmt.Errorf("search for files at %s yielded 0 results", ProjectPath+ScenesGlob)
 	}
-	baselineFiles, err := filepath.Glob(ProjectPath + BaselineGlob)
+	if err := verifyLocales(); err != nil {
+		return report, err
+	}
+	config, err := loadConfig()
 	if err != nil {
-		return false, fmt.Errorf("failed to list files at %s: %v", ProjectPath+BaselineGlob, err)
+		return report, err
 	}
-	if len(sceneFiles) == 0 {
-		return false, fmt.Errorf("search for files at %s yielded 0 results", ProjectPath+BaselineGlob)
+	quarantine, err := loadQuarantine(config)
+	if err != nil {
+		return report, err
 	}
+	targets := sceneTargets(sceneFiles, config)
 
 	tmpDir, cleanupTmpDir := lib.InitTmpDir()
 	if !RetainAssets {
+		cleanupTmpDir = onExit(cleanupTmpDir)
 		defer cleanupTmpDir()
 	}
 
-	// there must be a baseline for each scene if we're in test mode
-	var missingBaselines []string
-	for _, file := range sceneFiles {
-		target := strings.Replace(file, ".tscn", ".avi", 1)
-		if !slices.Contains(baselineFiles, target) {
-			missingBaselines = append(missingBaselines, file)
+	// there must be a baseline for each scene (and variant) if we're in test mode
+	mapping, err := mapBaselines(targets, config)
+	if err != nil {
+		return report, err
+	}
+	mapping.print()
+	if len(mapping.Missing) > 0 {
+		return report, fmt.Errorf("missing baselines for %d scenes, render them with godot-vrt baseline", len(mapping.Missing))
+	}
+
+	if err := prepareProject(ctx, tmpDir); err != nil {
+		return report, err
+	}
+
+	renders, err := renderTargets(ctx, targets, tmpDir, func(target sceneTarget) string {
+		return fmt.Sprintf("%s%s%s%s", tmpDir, target.Name(), "_actual", target.Ext())
+	})
+	if err != nil {
+		return report, fmt.Errorf("error rendering files: %v", err)
+	}
+
+	for i, target := range targets {
+		if err := testTarget(target, renders[i], tmpDir, &report); err != nil {
+			return report, err
 		}
 	}
-	if len(missingBaselines) > 0 {
-		return false, fmt.Errorf("missing baselines for scenes: %v", missingBaselines)
+
+	retriesPassed, err := retryTargets(ctx, targets, tmpDir, &report)
+	if err != nil {
+		return report, err
+	}
+	quarantineTargets(targets, quarantine, &report)
+	if err := lib.WriteManifest("vrt-results/manifest.json", testManifest(targets, report, retriesPassed)); err != nil {
+		return report, err
 	}
 
-	foundDiff := false
+	return report, nil
+}
 
-	for _, file := range sceneFiles {
-		sceneName := strings.Replace(file, ".tscn", "", 1)
+// retryTargets renders the targets that differ from their baseline or timed out Retries more times. A target passes
+// after all if enough of its retries match the baseline (see --retry-policy), and is then reported as flaky-passed.
+// It returns how many retries of each retried target matched.
+func retryTargets(ctx context.Context, targets []sceneTarget, tmpDir string, report *testReport) (map[string]int, error) {
+	passed := map[string]int{}
+	if Retries == 0 {
+		return passed, nil
+	}
+	var failing []sceneTarget
+	for _, target := range targets {
+		if slices.Contains(report.Failed, target.Name()) || slices.Contains(report.TimedOut, target.Name()) {
+			failing = append(failing, target)
+			passed[target.Name()] = 0
+		}
+	}
+	if len(failing) == 0 {
+		return passed, nil
+	}
 
-		actualPathFile := fmt.Sprintf("%s%s%s", tmpDir, sceneName, "_actual.avi")
-		renderedScene, err := lib.RenderScene(lib.RenderSceneArgs{
-			SceneFileFromProjectRoot: strings.Replace(file, lib.WithFolderSuffix(ProjectPath), "", 1),
-			OutputFile:               actualPathFile,
-			GodotBinary:              GodotExecutable,
-			Verbose:                  Verbose,
-			Frames:                   Frames,
-			ProjectPath:              ProjectPath,
+	for r := 0; r < Retries; r++ {
+		fmt.Printf("Retrying %d failed scenes (%d of %d)\n", len(failing), r+1, Retries)
+		renders, err := renderTargets(ctx, failing, tmpDir, func(target sceneTarget) string {
+			return fmt.Sprintf("%s%s_retry%d%s", tmpDir, target.Name(), r, target.Ext())
 		})
 		if err != nil {
-			return false, fmt.Errorf("error rendering file: %v", err)
+			return nil, fmt.Errorf("error rendering files: %v", err)
 		}
+		for i, target := range failing {
+			matches, err := retryMatches(target, renders[i], fmt.Sprintf("%s%s_retry%d_diff.avi", tmpDir, target.Name(), r))
+			if err != nil {
+				return nil, err
+			}
+			if matches {
+				passed[target.Name()]++
+			}
+		}
+	}
 
-		baseline, err := filepath.Abs(fmt.Sprintf("%s%s", sceneName, ".avi"))
+	for _, target := range failing {
+		name := target.Name()
+		pass, err := lib.RetriesPass(RetryPolicy, passed[name], Retries)
 		if err != nil {
-			return false, fmt.Errorf("error getting absolute path: %v", err)
+			return nil, err
+		}
+		fmt.Printf("%s matched its baseline in %d of %d retries\n", name, passed[name], Retries)
+		if !pass {
+			continue
+		}
+		isName := func(s string) bool { return s == name }
+		report.Failed = slices.DeleteFunc(report.Failed, isName)
+		report.TimedOut = slices.DeleteFunc(report.TimedOut, isName)
+		report.FlakyPassed = append(report.FlakyPassed, name)
+	}
+	return passed, nil
+}
+
+// retryMatches reports whether a retried render matches its baseline. Timeouts don't match.
+func retryMatches(target sceneTarget, render targetRender, diffOutFile string) (bool, error) {
+	var timeoutErr *lib.TimeoutError
+	if errors.As(render.Err, &timeoutErr) {
+		return false, nil
+	}
+	if render.Err != nil {
+		return false, fmt.Errorf("error rendering file: %v", render.Err)
+	}
+	baseline, err := filepath.Abs(target.BaselineFile())
+	if err != nil {
+		return false, fmt.Errorf("error getting absolute path: %v", err)
+	}
+	hasDiff, err := rendersDifferFromBaseline(target, render.Result.OutputFile, baseline, diffOutFile)
+	if err != nil {
+		return false, fmt.Errorf("error generating diff: %v", err)
+	}
+	return !hasDiff, nil
+}
+
+// quarantineTargets moves quarantined targets from the failure lists of the report to its quarantined list.
+func quarantineTargets(targets []sceneTarget, quarantine []lib.Quarantine, report *testReport) {
+	for _, target := range targets {
+		name := target.Name()
+		q, ok := lib.FindQuarantine(quarantine, target.SceneFileFromProjectRoot(), strings.Replace(name, ProjectPath, "", 1))
+		if !ok {
+			continue
+		}
+		report.Quarantined = append(report.Quarantined, quarantinedScene{Quarantine: q, Name: name, Status: report.status(name)})
+		isName := func(s string) bool { return s == name }
+		report.Failed = slices.DeleteFunc(report.Failed, isName)
+		report.TimedOut = slices.DeleteFunc(report.TimedOut, isName)
+		report.ScriptErrors = slices.DeleteFunc(report.ScriptErrors, isName)
+		report.LogChanged = slices.DeleteFunc(report.LogChanged, isName)
+		report.CheckpointsChanged = slices.DeleteFunc(report.CheckpointsChanged, isName)
+		report.EnvironmentChanged = slices.DeleteFunc(report.EnvironmentChanged, isName)
+	}
+}
+
+// status returns the manifest status of a scene.
+func (r testReport) status(name string) string {
+	switch {
+	case slices.Contains(r.TimedOut, name):
+		return lib.StatusTimedOut
+	case slices.Contains(r.Failed, name), slices.Contains(r.ScriptErrors, name),
+		slices.Contains(r.LogChanged, name), slices.Contains(r.CheckpointsChanged, name),
+		slices.Contains(r.EnvironmentChanged, name):
+		return lib.StatusFailed
+	case slices.Contains(r.FlakyPassed, name):
+		return lib.StatusFlakyPassed
+	}
+	for _, q := range r.Quarantined {
+		if q.Name == name {
+			return q.Status
 		}
-		diffOutFile := fmt.Sprintf("%s%s%s", tmpDir, sceneName, "_diff.avi")
-		hasDiff, err := lib.HasDiff(renderedScene, baseline, diffOutFile, Verbose, Frames)
+	}
+	return lib.StatusPassed
+}
+
+func testManifest(targets []sceneTarget, report testReport, retriesPassed map[string]int) lib.Manifest {
+	var manifest lib.Manifest
+	for _, target := range targets {
+		name := target.Name()
+		scene := lib.ManifestScene{Name: name, Status: report.status(name)}
+		if passed, retried := retriesPassed[name]; retried {
+			scene.Retries = Retries
+			scene.RetriesPassed = passed
+		}
+		scene.Quarantined = slices.ContainsFunc(report.Quarantined, func(q quarantinedScene) bool { return q.Name == name })
+		manifest.Scenes = append(manifest.Scenes, scene)
+	}
+	return manifest
+}
+
+// testTarget runs all comparisons for a rendered target and adds failures to the report.
+func testTarget(target sceneTarget, render targetRender, tmpDir string, report *testReport) error {
+	sceneName := target.Name()
+
+	rendered, err := render.Result, render.Err
+	var timeoutErr *lib.TimeoutError
+	if errors.As(err, &timeoutErr) {
+		report.TimedOut = append(report.TimedOut, sceneName)
+		fmt.Printf("⏱️ %s timed out after %v\n", sceneName, RenderTimeout)
+		l, err := lib.WriteRenderLog(sceneName, timeoutErr.Stdout, timeoutErr.Stderr, "vrt-results/")
 		if err != nil {
-			return false, fmt.Errorf("error generating diff: %v", err)
+			return fmt.Errorf("error writing render log: %v", err)
 		}
-		if hasDiff {
-			foundDiff = true
-			d, err := lib.GenerateComparison(sceneName, renderedScene, baseline, "vrt-results/", Verbose)
-			if err != nil {
-				return false, fmt.Errorf("error generating comparison: %v", err)
+		fmt.Println(l)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error rendering file: %v", err)
+	}
+	renderedScene := rendered.OutputFile
+
+	logFile, err := lib.WriteRenderLog(sceneName, rendered.Stdout, rendered.Stderr, "vrt-results/")
+	if err != nil {
+		return fmt.Errorf("error writing render log: %v", err)
+	}
+	scan := lib.ScanGodotLog(rendered.Stdout, rendered.Stderr)
+	if len(scan.Errors) > 0 || len(scan.Warnings) > 0 {
+		fmt.Printf("%s printed %d errors and %d warnings (see %s)\n", sceneName, len(scan.Errors), len(scan.Warnings), logFile)
+		for _, line := range scan.Errors {
+			fmt.Println("  " + line)
+		}
+	}
+	if FailOnScriptErrors && len(scan.Errors) > 0 {
+		report.ScriptErrors = append(report.ScriptErrors, sceneName)
+	}
+
+	baseline, err := filepath.Abs(target.BaselineFile())
+	if err != nil {
+		return fmt.Errorf("error getting absolute path: %v", err)
+	}
+
+	if err := checkEnvironment(target, baseline, report); err != nil {
+		return err
+	}
+
+	// golden logs are optional, we only compare against them if baseline created one
+	goldenLog := goldenLogFile(baseline)
+	if golden, err := os.ReadFile(goldenLog); err == nil {
+		logDiff := lib.UnifiedDiff(string(golden), lib.NormalizeGodotLog(rendered.Stdout, renderProjectPath), goldenLog, sceneName+" (actual)")
+		if logDiff != "" {
+			report.LogChanged = append(report.LogChanged, sceneName)
+			diffFile := "vrt-results/" + sceneName + ".log.diff"
+			if err := os.WriteFile(diffFile, []byte(logDiff), 0644); err != nil {
+				return fmt.Errorf("error writing log diff: %v", err)
 			}
-			fmt.Println(d)
+			fmt.Printf("%s printed a different log than its golden log (see %s):\n%s", sceneName, diffFile, logDiff)
 		}
 	}
 
-	return foundDiff, nil
+	hasDiff, err := rendersDifferFromBaseline(target, renderedScene, baseline, fmt.Sprintf("%s%s%s", tmpDir, sceneName, "_diff.avi"))
+	if err != nil {
+		return fmt.Errorf("error generating diff: %v", err)
+	}
+	if hasDiff {
+		report.Failed = append(report.Failed, sceneName)
+		var d string
+		if target.Screenshot {
+			d, err = lib.GenerateImageComparison(sceneName, renderedScene, baseline, "vrt-results/", Verbose)
+		} else {
+			d, err = lib.GenerateComparison(sceneName, renderedScene, baseline, "vrt-results/", Verbose)
+		}
+		if err != nil {
+			return fmt.Errorf("error generating comparison: %v", err)
+		}
+		fmt.Println(d)
+	}
+
+	if Checkpoints {
+		checkpointsChanged, err := testCheckpoints(sceneName, checkpointsDir(renderedScene), checkpointsDir(baseline))
+		if err != nil {
+			return err
+		}
+		if checkpointsChanged {
+			report.CheckpointsChanged = append(report.CheckpointsChanged, sceneName)
+		}
+	}
+	return nil
+}
+
+// checkEnvironment warns if a baseline was rendered in a different environment, because differences in the render
+// might be caused by the environment rather than the scene. With --strict-environment, the scene fails.
+func checkEnvironment(target sceneTarget, baseline string, report *testReport) error {
+	metadata, err := lib.ReadMetadata(lib.MetadataFile(baseline))
+	// baselines of older versions of godot-vrt don't have metadata
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	environment := renderEnvironment
+	if target.Screenshot {
+		environment.Encoder = ""
+	}
+	differences := environment.Differences(metadata.Environment)
+	if metadata.Frames != target.Frames() {
+		differences = append(differences, fmt.Sprintf("%d frames instead of %d", target.Frames(), metadata.Frames))
+	}
+	if len(differences) > 0 {
+		fmt.Printf("⚠️ %s renders in a different environment than its baseline:\n", target.Name())
+		for _, d := range differences {
+			fmt.Println("  " + d)
+		}
+		fmt.Println("  Run test in the baseline's environment, or re-render the baseline with godot-vrt baseline if the new environment is intended.")
+		if StrictEnvironment {
+			report.EnvironmentChanged = append(report.EnvironmentChanged, target.Name())
+		}
+	}
+
+	// The hashes only explain differences, they don't fail the scene.
+	if sceneHash, err := lib.FileHash(target.SceneFile); err == nil && sceneHash != metadata.Hashes.Scene {
+		fmt.Printf("%s changed since its baseline was rendered\n", target.SceneFile)
+	}
+	if baselineHash, err := lib.FileHash(baseline); err == nil && baselineHash != metadata.Hashes.Baseline {
+		fmt.Printf("⚠️ %s was modified after it was rendered, so its metadata might be outdated\n", baseline)
+	}
+	return nil
+}
+
+// rendersDifferFromBaseline is like rendersDiffer, but tolerates the calibrated noise of the baseline if it has a
+// noise map (see baseline --calibrate).
+func rendersDifferFromBaseline(target sceneTarget, rendered, baseline, diffOutFile string) (bool, error) {
+	noiseFile := noiseMapFile(baseline)
+	if _, err := os.Stat(noiseFile); os.IsNotExist(err) {
+		return rendersDiffer(target, rendered, baseline, diffOutFile)
+	}
+	noise, err := lib.LoadNoiseMap(noiseFile)
+	if err != nil {
+		return false, err
+	}
+	return lib.ExceedsNoise(rendered, baseline, noise, target.Frames(), target.CompareFrames, Verbose)
+}
+
+// testCheckpoints compares the checkpoint screenshots of a scene, and generates a comparison for each changed checkpoint.
+func testCheckpoints(sceneName, actualDir, baselineDir string) (bool, error) {
+	comparison, err := lib.CompareCheckpoints(actualDir, baselineDir)
+	if err != nil {
+		return false, fmt.Errorf("error comparing checkpoints: %v", err)
+	}
+	for _, name := range comparison.Changed {
+		d, err := lib.GenerateImageComparison(sceneName+".checkpoints/"+name, actualDir+name+".png", baselineDir+name+".png", "vrt-results/", Verbose)
+		if err != nil {
+			return false, fmt.Errorf("error generating comparison: %v", err)
+		}
+		fmt.Println(d)
+	}
+	if len(comparison.Missing) > 0 {
+		fmt.Printf("%s didn't reach the checkpoints %v\n", sceneName, comparison.Missing)
+	}
+	if len(comparison.New) > 0 {
+		fmt.Printf("%s reached checkpoints without baseline %v\n", sceneName, comparison.New)
+	}
+	return comparison.HasDiff(), nil
 }
@@ -22,7 +22,7 @@ func TestBaseline(t *testing.T) {
 	}
 
 	projectPath := "test-project/"
-	if err := lib.Validate(godotExecutable, projectPath); err != nil {
+	if _, err := lib.Validate(godotExecutable, projectPath, false, nil); err != nil {
 		t.Fatal("Test prerequisites not met", err)
 	}
 
@@ -33,8 +33,10 @@ func TestBaseline(t *testing.T) {
 		frames := 10
 
 		deleteFiles(t, projectPath+sceneName+".avi")
+		deleteFiles(t, projectPath+sceneName+".vrt.json")
 		t.Cleanup(func() {
 			deleteFiles(nil, projectPath+sceneName+".avi")
+			deleteFiles(nil, projectPath+sceneName+".vrt.json")
 		})
 
 		args := []string{
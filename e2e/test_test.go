@@ -21,7 +21,7 @@ func TestTest(t *testing.T) {
 	}
 
 	projectPath := "test-project/"
-	if err := lib.Validate(godotExecutable, projectPath); err != nil {
+	if _, err := lib.Validate(godotExecutable, projectPath, false, nil); err != nil {
 		t.Fatal("Test prerequisites not met", err)
 	}
 
@@ -0,0 +1,237 @@
+package lib
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+//go:embed godot/batch_runner.gd
+var batchRunnerScript string
+
+const (
+	sceneStartMarker = "VRT_SCENE_START"
+	sceneEndMarker   = "VRT_SCENE_END"
+	sceneErrorMarker = "VRT_SCENE_ERROR"
+
+	// batchFPS matches the default fps of Godot's movie writer.
+	batchFPS = 60
+)
+
+type BatchScene struct {
+	// Name identifies the scene in Godot's output. It must not contain line breaks.
+	Name                     string
+	SceneFileFromProjectRoot string
+	// OutputFile is a video, or a screenshot of the last frame if it's a .png.
+	OutputFile string
+	// Frames overrides the batch's number of frames for this scene if it's greater than 0.
+	Frames int
+	// CheckpointsDir is where the VRT autoload saves checkpoint screenshots (only with --checkpoints).
+	CheckpointsDir string
+}
+
+type RenderBatchArgs struct {
+	Scenes      []BatchScene
+	GodotBinary string
+	Verbose     bool
+	Frames      int
+	ProjectPath string
+	// Env holds additional environment variables (key=value) for Godot.
+	Env []string
+	// GodotArgs are additional arguments for Godot, e.g. to force a rendering driver.
+	GodotArgs []string
+	// TmpDir holds the manifest and the captured frames.
+	TmpDir string
+}
+
+// BatchResult is the outcome of rendering a single scene of a batch. Err is a *TimeoutError if the batch timed out
+// before the scene finished.
+type BatchResult struct {
+	Result RenderResult
+	Err    error
+}
+
+// RenderBatch renders all scenes in a single Godot process. An injected runner scene loads one scene after the other,
+// captures a fixed number of frames per scene, and prints markers between scenes. Afterwards the frames of each
+// scene are encoded into a video, and Godot's output is split per scene.
+func RenderBatch(ctx context.Context, args RenderBatchArgs) ([]BatchResult, error) {
+	runnerDir, err := os.MkdirTemp(args.ProjectPath, ".vrt_batch_")
+	if err != nil {
+		return nil, fmt.Errorf("error creating batch runner dir: %v", err)
+	}
+	defer os.RemoveAll(runnerDir)
+
+	runnerResPath := "res://" + filepath.Base(runnerDir) + "/"
+	runnerScene := fmt.Sprintf("[gd_scene load_steps=2 format=3]\n\n"+
+		"[ext_resource type=\"Script\" path=\"%srunner.gd\" id=\"1_runner\"]\n\n"+
+		"[node name=\"VRTBatchRunner\" type=\"Node\"]\nscript = ExtResource(\"1_runner\")\n", runnerResPath)
+	if err := os.WriteFile(filepath.Join(runnerDir, "runner.gd"), []byte(batchRunnerScript), 0644); err != nil {
+		return nil, fmt.Errorf("error writing batch runner: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(runnerDir, "runner.tscn"), []byte(runnerScene), 0644); err != nil {
+		return nil, fmt.Errorf("error writing batch runner: %v", err)
+	}
+
+	type manifestScene struct {
+		Name           string `json:"name"`
+		Scene          string `json:"scene"`
+		OutputDir      string `json:"output_dir"`
+		Frames         int    `json:"frames"`
+		CheckpointsDir string `json:"checkpoints_dir,omitempty"`
+	}
+	manifest := struct {
+		Scenes []manifestScene `json:"scenes"`
+	}{}
+
+	frameDirs := make([]string, len(args.Scenes))
+	for i, s := range args.Scenes {
+		frames := args.Frames
+		if s.Frames > 0 {
+			frames = s.Frames
+		}
+		frameDirs[i], err = filepath.Abs(filepath.Join(args.TmpDir, fmt.Sprintf("batch_frames_%d", i)))
+		if err != nil {
+			return nil, fmt.Errorf("error getting absolute path: %v", err)
+		}
+		// The tmp dir is reused by later batches (e.g. retries), which must not pick up frames of earlier scenes.
+		if err := os.RemoveAll(frameDirs[i]); err != nil {
+			return nil, fmt.Errorf("error clearing frames dir: %v", err)
+		}
+		if err := os.MkdirAll(frameDirs[i], 0755); err != nil {
+			return nil, fmt.Errorf("error creating frames dir: %v", err)
+		}
+		manifest.Scenes = append(manifest.Scenes, manifestScene{
+			Name:           s.Name,
+			Scene:          "res://" + filepath.ToSlash(s.SceneFileFromProjectRoot),
+			OutputDir:      filepath.ToSlash(frameDirs[i]),
+			Frames:         frames,
+			CheckpointsDir: filepath.ToSlash(s.CheckpointsDir),
+		})
+	}
+	manifestContent, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("error creating batch manifest: %v", err)
+	}
+	manifestFile, err := filepath.Abs(filepath.Join(args.TmpDir, "batch_manifest.json"))
+	if err != nil {
+		return nil, fmt.Errorf("error getting absolute path: %v", err)
+	}
+	if err := os.WriteFile(manifestFile, manifestContent, 0644); err != nil {
+		return nil, fmt.Errorf("error writing batch manifest: %v", err)
+	}
+
+	a := append(slices.Clone(args.GodotArgs),
+		// Like the movie writer, render with a fixed time step instead of real time.
+		"--fixed-fps", strconv.Itoa(batchFPS),
+		runnerResPath+"runner.tscn",
+		"--", "--vrt-manifest="+filepath.ToSlash(manifestFile),
+	)
+	if args.Verbose {
+		a = slices.Insert(a, 0, "--verbose")
+	}
+	stdout, stderr, err := executeCommandContext(ctx, &args.ProjectPath, args.Env, args.GodotBinary, a)
+	if args.Verbose {
+		fmt.Println(stdout)
+		fmt.Println(stderr)
+	}
+	timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded)
+	if err != nil && !timedOut {
+		return nil, fmt.Errorf("error rendering batch: %v %s", err, stderr)
+	}
+
+	stdoutPerScene := splitBatchOutput(stdout)
+	stderrPerScene := splitBatchOutput(stderr)
+
+	results := make([]BatchResult, len(args.Scenes))
+	for i, s := range args.Scenes {
+		out := stdoutPerScene[s.Name]
+		sceneStderr := stderrPerScene[s.Name].output
+		switch {
+		case !out.finished && timedOut:
+			results[i].Err = &TimeoutError{Stdout: out.output, Stderr: sceneStderr}
+		case !out.finished:
+			results[i].Err = fmt.Errorf("error rendering scene: scene did not finish in batch %s", sceneStderr)
+		case out.failed:
+			results[i].Err = fmt.Errorf("error rendering scene: cannot load %s %s", s.SceneFileFromProjectRoot, sceneStderr)
+		default:
+			if filepath.Ext(s.OutputFile) == ".png" {
+				err = keepLastFrame(frameDirs[i], s.OutputFile)
+			} else {
+				err = EncodeFrames(frameDirs[i], s.OutputFile, args.Verbose)
+			}
+			if err != nil {
+				results[i].Err = err
+				continue
+			}
+			results[i].Result = RenderResult{OutputFile: s.OutputFile, Stdout: out.output, Stderr: sceneStderr}
+		}
+	}
+	return results, nil
+}
+
+type batchSceneOutput struct {
+	output   string
+	finished bool
+	failed   bool
+}
+
+// splitBatchOutput splits Godot's output at the runner's markers. Output outside of markers (e.g. Godot's banner) is dropped.
+func splitBatchOutput(output string) map[string]batchSceneOutput {
+	scenes := map[string]batchSceneOutput{}
+	current := ""
+	var sb strings.Builder
+	for _, line := range strings.SplitAfter(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, sceneStartMarker+" "):
+			current = strings.TrimPrefix(trimmed, sceneStartMarker+" ")
+			sb.Reset()
+			scenes[current] = batchSceneOutput{}
+		case current != "" && (trimmed == sceneEndMarker+" "+current || trimmed == sceneErrorMarker+" "+current):
+			scenes[current] = batchSceneOutput{output: sb.String(), finished: true, failed: strings.HasPrefix(trimmed, sceneErrorMarker)}
+			current = ""
+		case current != "":
+			sb.WriteString(line)
+			scenes[current] = batchSceneOutput{output: sb.String()}
+		}
+	}
+	return scenes
+}
+
+// EncodeFrames encodes the PNG frames of a directory (frame_00001.png, ...) into a video like Godot's movie writer creates.
+func EncodeFrames(framesDir, outFile string, verbose bool) error {
+	if err := os.MkdirAll(filepath.Dir(outFile), 0755); err != nil {
+		return fmt.Errorf("error creating dir: %v", err)
+	}
+	args := []string{
+		"-y",
+		"-framerate", strconv.Itoa(batchFPS),
+		"-i", filepath.Join(framesDir, "frame_%05d.png"),
+		"-c:v", "mjpeg",
+		"-q:v", "2",
+		outFile,
+	}
+	if !verbose {
+		args = slices.Insert(args, 0, "-loglevel", "error")
+	}
+	_, stderr, err := executeCommandUnsafe(nil, "ffmpeg", args)
+	if err != nil {
+		return fmt.Errorf("encoding frames: %v %s", err, stderr)
+	}
+
+	fileInfo, err := os.Stat(outFile)
+	if err != nil {
+		return fmt.Errorf("error getting rendered file info: %v", err)
+	}
+	if fileInfo.Size() == 0 {
+		return fmt.Errorf("error: rendered file is empty")
+	}
+	return nil
+}
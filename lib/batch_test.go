@@ -0,0 +1,27 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitBatchOutput(t *testing.T) {
+	output := "Godot Engine v4.4.1.stable.official.49a5bc7b6 - https://godotengine.org\n" +
+		"VRT_SCENE_START vrt/menu\n" +
+		"menu ready\n" +
+		"VRT_SCENE_END vrt/menu\n" +
+		"VRT_SCENE_START vrt/missing\n" +
+		"VRT_SCENE_ERROR vrt/missing\n" +
+		"VRT_SCENE_START vrt/door.open\n" +
+		"door opening\n"
+
+	got := splitBatchOutput(output)
+	want := map[string]batchSceneOutput{
+		"vrt/menu":      {output: "menu ready\n", finished: true},
+		"vrt/missing":   {finished: true, failed: true},
+		"vrt/door.open": {output: "door opening\n"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitBatchOutput() got %+v, want %+v", got, want)
+	}
+}
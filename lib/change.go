@@ -0,0 +1,93 @@
+package lib
+
+import (
+	"fmt"
+	"image"
+)
+
+// Change describes how much a render differs from its baseline.
+type Change struct {
+	// Frames is the number of compared frames, ChangedFrames the number of frames with at least one changed pixel.
+	Frames        int
+	ChangedFrames int
+	// ChangedPixels is the largest number of changed pixels in a single frame, out of Pixels per frame.
+	ChangedPixels int
+	Pixels        int
+	// MaxDifference is the largest difference of any colour channel (0-255).
+	MaxDifference uint8
+	// Resized is set if the render and the baseline have different sizes, which makes the other fields meaningless.
+	Resized bool
+}
+
+func (c Change) String() string {
+	if c.Resized {
+		return "the size changed"
+	}
+	percent := 0.0
+	if c.Pixels > 0 {
+		percent = 100 * float64(c.ChangedPixels) / float64(c.Pixels)
+	}
+	return fmt.Sprintf("%d of %d frames changed, up to %d pixels (%.2f%%) per frame, max difference %d",
+		c.ChangedFrames, c.Frames, c.ChangedPixels, percent, c.MaxDifference)
+}
+
+// MeasureChange compares the selected frames of a render against its baseline pixel by pixel. Differences within a
+// pixel's calibrated noise are ignored if a noise map is given (see MeasureNoise), noise may be nil.
+func MeasureChange(rendered, baseline string, noise *image.Gray, duration int, frames FrameSelection, verbose bool) (Change, error) {
+	var change Change
+	renderedFrames, cleanupRendered, err := renderFrames(rendered, duration, verbose)
+	if err != nil {
+		return change, err
+	}
+	defer cleanupRendered()
+	baselineFrames, cleanupBaseline, err := renderFrames(baseline, duration, verbose)
+	if err != nil {
+		return change, err
+	}
+	defer cleanupBaseline()
+
+	frameCount := min(len(renderedFrames), len(baselineFrames))
+	for frame := 0; frame < frameCount; frame++ {
+		if frameCount > 1 && !frames.Includes(frame) {
+			continue
+		}
+		a, err := decodeImage(renderedFrames[frame])
+		if err != nil {
+			return change, err
+		}
+		b, err := decodeImage(baselineFrames[frame])
+		if err != nil {
+			return change, err
+		}
+		if a.Bounds().Dx() != b.Bounds().Dx() || a.Bounds().Dy() != b.Bounds().Dy() {
+			return Change{Resized: true}, nil
+		}
+		if noise != nil && (a.Bounds().Dx() != noise.Bounds().Dx() || a.Bounds().Dy() != noise.Bounds().Dy()) {
+			return change, fmt.Errorf("noise map has a different size than the render: %v and %v", noise.Bounds(), a.Bounds())
+		}
+
+		change.Frames++
+		change.Pixels = a.Bounds().Dx() * a.Bounds().Dy()
+		changed := 0
+		for y := 0; y < a.Bounds().Dy(); y++ {
+			for x := 0; x < a.Bounds().Dx(); x++ {
+				r1, g1, b1, a1 := a.At(x+a.Bounds().Min.X, y+a.Bounds().Min.Y).RGBA()
+				r2, g2, b2, a2 := b.At(x+b.Bounds().Min.X, y+b.Bounds().Min.Y).RGBA()
+				diff := max(absDiff(r1>>8, r2>>8), absDiff(g1>>8, g2>>8), absDiff(b1>>8, b2>>8), absDiff(a1>>8, a2>>8))
+				tolerance := uint32(0)
+				if noise != nil {
+					tolerance = uint32(noise.GrayAt(x+noise.Bounds().Min.X, y+noise.Bounds().Min.Y).Y)
+				}
+				if diff > tolerance {
+					changed++
+					change.MaxDifference = max(change.MaxDifference, uint8(diff))
+				}
+			}
+		}
+		if changed > 0 {
+			change.ChangedFrames++
+			change.ChangedPixels = max(change.ChangedPixels, changed)
+		}
+	}
+	return change, nil
+}
@@ -0,0 +1,63 @@
+package lib
+
+import (
+	"image"
+	"image/color"
+	"path/filepath"
+	"testing"
+)
+
+func TestMeasureChange(t *testing.T) {
+	dir := t.TempDir()
+	save := func(name string, size int, pixels map[image.Point]color.RGBA) string {
+		img := image.NewRGBA(image.Rect(0, 0, size, size))
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				img.Set(x, y, color.RGBA{A: 255})
+			}
+		}
+		for p, c := range pixels {
+			img.Set(p.X, p.Y, c)
+		}
+		path := filepath.Join(dir, name)
+		if err := SavePNG(img, path); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	baseline := save("baseline.png", 4, nil)
+	rendered := save("rendered.png", 4, map[image.Point]color.RGBA{
+		{0, 0}: {R: 200, A: 255},
+		{1, 0}: {G: 3, A: 255},
+	})
+
+	change, err := MeasureChange(rendered, baseline, nil, 1, FrameSelection{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Change{Frames: 1, ChangedFrames: 1, ChangedPixels: 2, Pixels: 16, MaxDifference: 200}
+	if change != want {
+		t.Errorf("MeasureChange() = %+v, want %+v", change, want)
+	}
+	if got := change.String(); got != "1 of 1 frames changed, up to 2 pixels (12.50%) per frame, max difference 200" {
+		t.Errorf("String() = %q", got)
+	}
+
+	noise := image.NewGray(image.Rect(0, 0, 4, 4))
+	noise.SetGray(1, 0, color.Gray{Y: 5})
+	change, err = MeasureChange(rendered, baseline, noise, 1, FrameSelection{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.ChangedPixels != 1 {
+		t.Errorf("ChangedPixels = %d, want 1 when the noise map tolerates the second pixel", change.ChangedPixels)
+	}
+
+	change, err = MeasureChange(save("large.png", 8, nil), baseline, nil, 1, FrameSelection{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !change.Resized || change.String() != "the size changed" {
+		t.Errorf("expected a resized change, got %+v", change)
+	}
+}
@@ -0,0 +1,106 @@
+package lib
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+//go:embed godot/checkpoint.gd
+var checkpointScript string
+
+// CheckpointsArg returns the user argument (i.e. after "--") that tells the VRT autoload where to save checkpoints.
+func CheckpointsArg(dir string) string {
+	return "--vrt-checkpoints=" + filepath.ToSlash(dir)
+}
+
+// InjectCheckpointAutoload registers the VRT autoload in the project through override.cfg, so that scenes can call
+// VRT.checkpoint("name"). The returned function removes it again.
+func InjectCheckpointAutoload(projectPath string) (func(), error) {
+	dir, err := os.MkdirTemp(projectPath, ".vrt_checkpoints_")
+	if err != nil {
+		return nil, fmt.Errorf("error creating checkpoint autoload dir: %v", err)
+	}
+	removeDir := func() {
+		if err := os.RemoveAll(dir); err != nil {
+			fmt.Printf("error removing checkpoint autoload dir: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "vrt.gd"), []byte(checkpointScript), 0644); err != nil {
+		removeDir()
+		return nil, fmt.Errorf("error writing checkpoint autoload: %v", err)
+	}
+
+	restore, err := injectOverrideConfig(projectPath, fmt.Sprintf("[autoload]\n\nVRT=\"*res://%s/vrt.gd\"\n", filepath.Base(dir)))
+	if err != nil {
+		removeDir()
+		return nil, err
+	}
+	return func() {
+		restore()
+		removeDir()
+	}, nil
+}
+
+// ListCheckpoints returns the names of all checkpoint screenshots in a directory.
+func ListCheckpoints(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.png"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checkpoints at %s: %v", dir, err)
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, strings.TrimSuffix(filepath.Base(f), ".png"))
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+type CheckpointComparison struct {
+	// Changed lists checkpoints whose screenshot differs from the baseline.
+	Changed []string
+	// Missing lists checkpoints that have a baseline, but weren't reached while rendering.
+	Missing []string
+	// New lists checkpoints without a baseline.
+	New []string
+}
+
+func (c CheckpointComparison) HasDiff() bool {
+	return len(c.Changed) > 0 || len(c.Missing) > 0 || len(c.New) > 0
+}
+
+// CompareCheckpoints compares the checkpoint screenshots of a render against the baseline's screenshots.
+func CompareCheckpoints(actualDir, baselineDir string) (CheckpointComparison, error) {
+	var comparison CheckpointComparison
+	actual, err := ListCheckpoints(actualDir)
+	if err != nil {
+		return comparison, err
+	}
+	baseline, err := ListCheckpoints(baselineDir)
+	if err != nil {
+		return comparison, err
+	}
+
+	for _, name := range baseline {
+		if !slices.Contains(actual, name) {
+			comparison.Missing = append(comparison.Missing, name)
+			continue
+		}
+		differ, err := ImagesDiffer(filepath.Join(actualDir, name+".png"), filepath.Join(baselineDir, name+".png"))
+		if err != nil {
+			return comparison, err
+		}
+		if differ {
+			comparison.Changed = append(comparison.Changed, name)
+		}
+	}
+	for _, name := range actual {
+		if !slices.Contains(baseline, name) {
+			comparison.New = append(comparison.New, name)
+		}
+	}
+	return comparison, nil
+}
@@ -0,0 +1,57 @@
+package lib
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func writePNG(t *testing.T, path string, c color.Color) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompareCheckpoints(t *testing.T) {
+	actual := t.TempDir()
+	baseline := t.TempDir()
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+
+	writePNG(t, filepath.Join(baseline, "start.png"), red)
+	writePNG(t, filepath.Join(actual, "start.png"), red)
+	writePNG(t, filepath.Join(baseline, "after_door_opens.png"), red)
+	writePNG(t, filepath.Join(actual, "after_door_opens.png"), blue)
+	writePNG(t, filepath.Join(baseline, "boss_defeated.png"), red)
+	writePNG(t, filepath.Join(actual, "credits.png"), red)
+
+	got, err := CompareCheckpoints(actual, baseline)
+	if err != nil {
+		t.Fatalf("CompareCheckpoints() error = %v", err)
+	}
+	if !slices.Equal(got.Changed, []string{"after_door_opens"}) {
+		t.Errorf("CompareCheckpoints() changed = %v", got.Changed)
+	}
+	if !slices.Equal(got.Missing, []string{"boss_defeated"}) {
+		t.Errorf("CompareCheckpoints() missing = %v", got.Missing)
+	}
+	if !slices.Equal(got.New, []string{"credits"}) {
+		t.Errorf("CompareCheckpoints() new = %v", got.New)
+	}
+}
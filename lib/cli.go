@@ -2,16 +2,40 @@ package lib
 
 import (
 	"bytes"
+	"context"
 	"os"
 	"os/exec"
+	"time"
 )
 
+// commandCtx is the context of commands that don't get one of their own, such as ffmpeg. See CancelCommandsWith.
+var commandCtx = context.Background()
+
+// CancelCommandsWith kills the commands that don't get a context of their own (e.g. ffmpeg) once ctx is done,
+// e.g. because godot-vrt was interrupted.
+func CancelCommandsWith(ctx context.Context) {
+	commandCtx = ctx
+}
+
 func executeCommandUnsafe(dir *string, program string, args []string) (string, string, error) {
+	return executeCommandContext(commandCtx, dir, nil, program, args)
+}
+
+// executeCommandContext runs a command like executeCommandUnsafe, but kills the command's whole process group
+// once the context is done. Child processes (e.g. spawned by Godot) are terminated as well.
+// env holds additional environment variables (key=value) on top of the current process's environment.
+func executeCommandContext(ctx context.Context, dir *string, env []string, program string, args []string) (string, string, error) {
 
-	cmd := exec.Command(program, args...)
+	cmd := exec.CommandContext(ctx, program, args...)
 	if dir != nil {
 		cmd.Dir = *dir
 	}
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	useProcessGroup(cmd)
+	// Don't wait forever for output pipes that are held open by orphaned children.
+	cmd.WaitDelay = 5 * time.Second
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
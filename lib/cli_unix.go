@@ -0,0 +1,17 @@
+//go:build !windows
+
+package lib
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// useProcessGroup starts the command in its own process group, so that cancelling it kills all of its children too.
+func useProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		// A negative pid addresses the whole process group.
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
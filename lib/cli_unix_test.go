@@ -0,0 +1,24 @@
+//go:build !windows
+
+package lib
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestExecuteCommandContextKillsProcessGroup(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	// The background sleep keeps stdout open, so this only returns early if the whole group is killed.
+	_, _, err := executeCommandContext(ctx, nil, nil, "sh", []string{"-c", "sleep 30 & sleep 30"})
+	if err == nil {
+		t.Fatal("expected an error for a killed command")
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("command was not killed in time, took %v", elapsed)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build windows
+
+package lib
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// useProcessGroup makes cancelling the command kill its whole process tree, so that children of Godot don't survive.
+// Windows has no process groups like unix, so the tree is killed with taskkill.
+func useProcessGroup(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		kill := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid))
+		if err := kill.Run(); err != nil {
+			// e.g. taskkill isn't available, so at least kill the process itself
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+}
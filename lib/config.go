@@ -0,0 +1,83 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// Config holds settings that can't be expressed with command line flags, such as settings for individual scenes.
+type Config struct {
+	// Scenes maps a scene path relative from the project root (e.g. vrt/health_bar.tscn) to its settings.
+	Scenes map[string]SceneConfig `json:"scenes"`
+	// Quarantine lists known-flaky scenes whose failures don't fail the test run.
+	Quarantine []Quarantine `json:"quarantine"`
+}
+
+type SceneConfig struct {
+	Variants []Variant `json:"variants"`
+	// Screenshot renders a single frame as a png instead of a video (like --screenshot).
+	Screenshot bool `json:"screenshot"`
+	// WarmupFrames are rendered before the screenshot is taken (like --warmup-frames).
+	WarmupFrames int `json:"warmup_frames"`
+	// SkipFrames excludes the first frames from the comparison (like --skip-frames).
+	SkipFrames int `json:"skip_frames"`
+	// CompareFrames limits the comparison to these frames, e.g. [10, "20-40"]. The full video is still recorded.
+	CompareFrames []FrameRange `json:"compare_frames"`
+	// Baseline overrides the location of the scene's baseline, relative from the project root (e.g.
+	// baselines/menu.avi). Variants and locales are added before the extension (e.g. baselines/menu.de.avi).
+	Baseline string `json:"baseline"`
+}
+
+// Variant renders a scene with a set of property overrides. Each variant gets its own baseline.
+type Variant struct {
+	Name string `json:"name"`
+	// Overrides maps a node path relative to the scene's root node (use "." for the root node itself)
+	// to the properties that should be overridden. Numbers and booleans are used as they are, strings
+	// are written verbatim as Godot values (e.g. "Vector2(10, 20)" or "\"Hello\"").
+	Overrides map[string]map[string]any `json:"overrides"`
+}
+
+var variantNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func LoadConfig(path string) (Config, error) {
+	var config Config
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("error reading config file %s: %v", path, err)
+	}
+	if err := json.Unmarshal(content, &config); err != nil {
+		return config, fmt.Errorf("error parsing config file %s: %v", path, err)
+	}
+
+	for scene, sceneConfig := range config.Scenes {
+		if sceneConfig.WarmupFrames < 0 {
+			return config, fmt.Errorf("invalid warmup_frames for scene %s: must not be negative", scene)
+		}
+		if sceneConfig.SkipFrames < 0 {
+			return config, fmt.Errorf("invalid skip_frames for scene %s: must not be negative", scene)
+		}
+		names := map[string]bool{}
+		for _, v := range sceneConfig.Variants {
+			if !variantNamePattern.MatchString(v.Name) {
+				return config, fmt.Errorf("invalid variant name %q for scene %s: only letters, digits, _ and - are allowed", v.Name, scene)
+			}
+			if names[v.Name] {
+				return config, fmt.Errorf("duplicate variant name %q for scene %s", v.Name, scene)
+			}
+			names[v.Name] = true
+		}
+	}
+	for _, q := range config.Quarantine {
+		if err := q.validate(); err != nil {
+			return config, fmt.Errorf("error in config file %s: %v", path, err)
+		}
+	}
+	return config, nil
+}
+
+// Scene returns the settings for a scene, or empty settings if the config doesn't mention it.
+func (c Config) Scene(sceneFileFromProjectRoot string) SceneConfig {
+	return c.Scenes[sceneFileFromProjectRoot]
+}
@@ -11,7 +11,7 @@ import (
 	"strings"
 )
 
-func HasDiff(renderedVideo, baselineVideo, outFile string, verbose bool, duration int) (bool, error) {
+func HasDiff(renderedVideo, baselineVideo, outFile string, verbose bool, duration int, frames FrameSelection) (bool, error) {
 	if err := os.MkdirAll(filepath.Dir(outFile), 0700); err != nil {
 		return false, fmt.Errorf("error creating dir: %v", err)
 	}
@@ -41,39 +41,15 @@ func HasDiff(renderedVideo, baselineVideo, outFile string, verbose bool, duratio
 		return false, fmt.Errorf("error: diff file is empty")
 	}
 
-	return HasMultiplePixelValues(outFile, duration, verbose)
+	return HasMultiplePixelValues(outFile, duration, frames, verbose)
 }
 
-func HasMultiplePixelValues(videoPath string, duration int, verbose bool) (bool, error) {
-	// Create temporary directory for extracted frames
-	//tempDir, err := os.MkdirTemp(config.TmpDir, "video_frames_")
-	tempDir, err := os.MkdirTemp(filepath.Dir(videoPath), fmt.Sprintf(".frames_%s_", strings.Replace(filepath.Base(videoPath), ".avi", "", 1)))
+// HasMultiplePixelValues checks whether the selected frames of a diff video contain more than one colour, i.e. whether
+// there is a difference. All frames are extracted, but only the selected ones are evaluated.
+func HasMultiplePixelValues(videoPath string, duration int, frames FrameSelection, verbose bool) (bool, error) {
+	frameFiles, err := ExtractFrames(videoPath, duration, verbose)
 	if err != nil {
-		return false, fmt.Errorf("failed to create temp directory: %v", err)
-	}
-
-	// Extract frames using ffmpeg
-	args := []string{
-		"-i", videoPath,
-		"-vf", fmt.Sprintf("select=gte(n\\,0)"),
-		"-vframes", strconv.Itoa(duration),
-		"-vsync", "0",
-		"-f", "image2",
-		fmt.Sprintf("%s/frame_%%05d.png", tempDir),
-	}
-
-	if !verbose {
-		args = slices.Insert(args, 0, "-loglevel", "error")
-	}
-
-	_, stderr, err := executeCommandUnsafe(nil, "ffmpeg", args)
-	if err != nil {
-		return false, fmt.Errorf("failed to extract frames: %v - %s", err, stderr)
-	}
-
-	frameFiles, err := filepath.Glob(fmt.Sprintf("%s/frame_*.png", tempDir))
-	if err != nil {
-		return false, fmt.Errorf("failed to list frame files: %v", err)
+		return false, err
 	}
 
 	// The differ_test fails because somehow it only yields 30 frames.
@@ -83,7 +59,11 @@ func HasMultiplePixelValues(videoPath string, duration int, verbose bool) (bool,
 	//	return false, fmt.Errorf("expected %d frames, got %d", duration, len(frameFiles))
 	//}
 
-	for _, framePath := range frameFiles {
+	// The frame files are numbered, so their order matches the order of the frames.
+	for frame, framePath := range frameFiles {
+		if !frames.Includes(frame) {
+			continue
+		}
 		file, err := os.Open(framePath)
 		if err != nil {
 			return false, fmt.Errorf("failed to open frame %s: %v", framePath, err)
@@ -122,11 +102,54 @@ func HasMultiplePixelValues(videoPath string, duration int, verbose bool) (bool,
 	return false, nil
 }
 
+// ExtractFrames saves the frames of a video as png files into a new .frames_ directory next to the video,
+// and returns the files in the order of the frames.
+func ExtractFrames(videoPath string, duration int, verbose bool) ([]string, error) {
+	// Create temporary directory for extracted frames
+	//tempDir, err := os.MkdirTemp(config.TmpDir, "video_frames_")
+	tempDir, err := os.MkdirTemp(filepath.Dir(videoPath), fmt.Sprintf(".frames_%s_", strings.Replace(filepath.Base(videoPath), ".avi", "", 1)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %v", err)
+	}
+
+	// Extract frames using ffmpeg
+	args := []string{
+		"-i", videoPath,
+		"-vf", fmt.Sprintf("select=gte(n\\,0)"),
+		"-vframes", strconv.Itoa(duration),
+		"-vsync", "0",
+		"-f", "image2",
+		fmt.Sprintf("%s/frame_%%05d.png", tempDir),
+	}
+
+	if !verbose {
+		args = slices.Insert(args, 0, "-loglevel", "error")
+	}
+
+	_, stderr, err := executeCommandUnsafe(nil, "ffmpeg", args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract frames: %v - %s", err, stderr)
+	}
+
+	frameFiles, err := filepath.Glob(fmt.Sprintf("%s/frame_*.png", tempDir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list frame files: %v", err)
+	}
+	return frameFiles, nil
+}
+
 func GenerateComparison(sceneName, rendered, baseline, resultDir string, verbose bool) (string, error) {
+	return generateComparison(fmt.Sprintf("%s%s%s", resultDir, sceneName, ".avi"), rendered, baseline, verbose)
+}
+
+// GenerateImageComparison creates a PNG with the baseline, the rendered image and their difference side by side.
+func GenerateImageComparison(name, rendered, baseline, resultDir string, verbose bool) (string, error) {
+	return generateComparison(fmt.Sprintf("%s%s%s", resultDir, name, ".png"), rendered, baseline, verbose)
+}
 
-	outFile := fmt.Sprintf("%s%s%s", resultDir, sceneName, ".avi")
+func generateComparison(outFile, rendered, baseline string, verbose bool) (string, error) {
 	if err := os.MkdirAll(filepath.Dir(outFile), 0755); err != nil {
-		return "", fmt.Errorf("error creating dir: %v %s", err, resultDir)
+		return "", fmt.Errorf("error creating dir: %v %s", err, filepath.Dir(outFile))
 	}
 	args := []string{
 		"-y",
@@ -146,7 +169,7 @@ func GenerateComparison(sceneName, rendered, baseline, resultDir string, verbose
 	_, stderr, err := executeCommandUnsafe(nil, "ffmpeg", args)
 
 	if err != nil {
-		return "", fmt.Errorf("generating comparison video: %v %s", err, stderr)
+		return "", fmt.Errorf("generating comparison: %v %s", err, stderr)
 	}
 
 	fileInfo, err := os.Stat(outFile)
@@ -159,3 +182,48 @@ func GenerateComparison(sceneName, rendered, baseline, resultDir string, verbose
 
 	return outFile, nil
 }
+
+// ImagesDiffer compares two images pixel by pixel. Images of different sizes always differ.
+//
+// Unlike HasDiff, which tolerates a diff video of a single colour because the lossy video encoding can shift all
+// colours a bit, screenshots are lossless pngs and must match exactly. A uniform tolerance would also miss changes
+// that affect every pixel the same way, e.g. a different background colour.
+func ImagesDiffer(a, b string) (bool, error) {
+	imgA, err := decodeImage(a)
+	if err != nil {
+		return false, err
+	}
+	imgB, err := decodeImage(b)
+	if err != nil {
+		return false, err
+	}
+
+	boundsA, boundsB := imgA.Bounds(), imgB.Bounds()
+	if boundsA.Dx() != boundsB.Dx() || boundsA.Dy() != boundsB.Dy() {
+		return true, nil
+	}
+	for y := 0; y < boundsA.Dy(); y++ {
+		for x := 0; x < boundsA.Dx(); x++ {
+			r1, g1, b1, a1 := imgA.At(x+boundsA.Min.X, y+boundsA.Min.Y).RGBA()
+			r2, g2, b2, a2 := imgB.At(x+boundsB.Min.X, y+boundsB.Min.Y).RGBA()
+			if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func decodeImage(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image %s: %v", path, err)
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image %s: %v", path, err)
+	}
+	return img, nil
+}
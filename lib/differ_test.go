@@ -1,6 +1,8 @@
 package lib
 
 import (
+	"image"
+	"image/color"
 	"os"
 	"path/filepath"
 	"testing"
@@ -40,7 +42,7 @@ func TestHasMultiplePixelValues(t *testing.T) {
 				}
 			})
 
-			got, err := HasMultiplePixelValues(tt.video, tt.duration, false)
+			got, err := HasMultiplePixelValues(tt.video, tt.duration, FrameSelection{}, false)
 			if err != nil {
 				t.Errorf("HasMultiplePixelValues() error = %v", err)
 				return
@@ -51,3 +53,49 @@ func TestHasMultiplePixelValues(t *testing.T) {
 		})
 	}
 }
+
+func TestImagesDiffer(t *testing.T) {
+	dir := t.TempDir()
+	save := func(name string, size int, fill color.RGBA, spot *color.RGBA) string {
+		img := image.NewRGBA(image.Rect(0, 0, size, size))
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				img.Set(x, y, fill)
+			}
+		}
+		if spot != nil {
+			img.Set(1, 1, *spot)
+		}
+		path := filepath.Join(dir, name)
+		if err := SavePNG(img, path); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	gray := color.RGBA{R: 100, G: 100, B: 100, A: 255}
+	baseline := save("baseline.png", 4, gray, nil)
+
+	tests := []struct {
+		name     string
+		rendered string
+		want     bool
+	}{
+		{"same image", save("same.png", 4, gray, nil), false},
+		// pngs are lossless, so unlike HasDiff, a uniform difference of all pixels isn't tolerated
+		{"uniformly shifted colours", save("shifted.png", 4, color.RGBA{R: 102, G: 101, B: 100, A: 255}, nil), true},
+		{"different background", save("background.png", 4, color.RGBA{B: 255, A: 255}, nil), true},
+		{"changed pixel", save("changed.png", 4, gray, &color.RGBA{R: 255, A: 255}), true},
+		{"different size", save("large.png", 8, gray, nil), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ImagesDiffer(tt.rendered, baseline)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ImagesDiffer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package lib
+
+// FreeDiskSpace isn't implemented on this system, so the free disk space isn't checked.
+func FreeDiskSpace(dir string) (uint64, error) {
+	return 0, errDiskSpaceUnsupported
+}
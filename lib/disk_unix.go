@@ -0,0 +1,15 @@
+//go:build linux || darwin || freebsd
+
+package lib
+
+import "syscall"
+
+// FreeDiskSpace returns the number of bytes available to unprivileged users on the disk of dir.
+func FreeDiskSpace(dir string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+	// The field types differ between systems, e.g. Bavail is signed on freebsd.
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
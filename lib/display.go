@@ -0,0 +1,137 @@
+package lib
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+//go:embed godot/display_check.gd
+var displayCheckScript string
+
+// VirtualDisplayGodotArgs make Godot render on a virtual X display: the Compatibility renderer runs on Mesa's
+// software rasterizer (llvmpipe), while Forward+ and Mobile need a Vulkan capable GPU.
+var VirtualDisplayGodotArgs = []string{
+	"--display-driver", "x11",
+	"--rendering-method", "gl_compatibility",
+	"--rendering-driver", "opengl3",
+}
+
+// VirtualDisplay is an Xvfb instance that Godot can render to on machines without a display or GPU.
+type VirtualDisplay struct {
+	// Display is the X display name, e.g. ":99".
+	Display string
+	cmd     *exec.Cmd
+}
+
+// StartVirtualDisplay starts Xvfb on a free display number, and waits until it accepts connections.
+func StartVirtualDisplay(verbose bool) (*VirtualDisplay, error) {
+	if err := VerifyBinary("Xvfb"); err != nil {
+		return nil, fmt.Errorf("virtual displays require Xvfb (e.g. apt install xvfb): %v", err)
+	}
+
+	// Xvfb picks a free display number and writes it to the file descriptor given with -displayfd once it's ready.
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("error creating pipe: %v", err)
+	}
+	defer r.Close()
+	cmd := exec.Command("Xvfb", "-displayfd", "3", "-screen", "0", "1920x1080x24", "-nolisten", "tcp")
+	// ExtraFiles[0] becomes file descriptor 3 of the child.
+	cmd.ExtraFiles = []*os.File{w}
+	if verbose {
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+	}
+	killWithParent(cmd)
+	err = cmd.Start()
+	w.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error starting Xvfb: %v", err)
+	}
+
+	display := &VirtualDisplay{cmd: cmd}
+	number := make(chan string, 1)
+	go func() {
+		// Reading fails once Xvfb exits without writing a display number.
+		line, _ := bufio.NewReader(r).ReadString('\n')
+		number <- strings.TrimSpace(line)
+	}()
+	select {
+	case n := <-number:
+		if n == "" {
+			display.Stop()
+			return nil, fmt.Errorf("Xvfb exited before it was ready (run with --verbose to see its output)")
+		}
+		display.Display = ":" + n
+	case <-time.After(10 * time.Second):
+		display.Stop()
+		return nil, fmt.Errorf("Xvfb wasn't ready after 10s")
+	}
+	return display, nil
+}
+
+// Env returns the environment variables that make Godot render on the virtual display with Mesa's software
+// rasterizer.
+func (d *VirtualDisplay) Env() []string {
+	return []string{
+		"DISPLAY=" + d.Display,
+		"LIBGL_ALWAYS_SOFTWARE=1",
+		"GALLIUM_DRIVER=llvmpipe",
+	}
+}
+
+// Stop terminates Xvfb.
+func (d *VirtualDisplay) Stop() {
+	if d.cmd.Process != nil {
+		d.cmd.Process.Kill()
+		d.cmd.Wait()
+	}
+}
+
+// VerifyDisplay starts Godot on the virtual display and returns the name of the video adapter it renders with
+// (e.g. "llvmpipe (LLVM 15.0.7, 256 bits)").
+func VerifyDisplay(godotPath string, display *VirtualDisplay) (string, error) {
+	adapter, err := VideoAdapter(godotPath, display.Env(), VirtualDisplayGodotArgs)
+	if err != nil {
+		return "", fmt.Errorf("godot can't render on the virtual display %s: %v", display.Display, err)
+	}
+	return adapter, nil
+}
+
+// VideoAdapter starts Godot in an empty project and returns the name of the video adapter it renders with.
+// env and godotArgs are passed to Godot, e.g. to render on a virtual display.
+func VideoAdapter(godotPath string, env, godotArgs []string) (string, error) {
+	projectDir, err := os.MkdirTemp("", "vrt_display_check_")
+	if err != nil {
+		return "", fmt.Errorf("error creating display check project: %v", err)
+	}
+	defer os.RemoveAll(projectDir)
+	if err := os.WriteFile(filepath.Join(projectDir, "project.godot"), []byte("config_version=5\n"), 0644); err != nil {
+		return "", fmt.Errorf("error creating display check project: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "display_check.gd"), []byte(displayCheckScript), 0644); err != nil {
+		return "", fmt.Errorf("error creating display check project: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	a := append(slices.Clone(godotArgs), "-s", "res://display_check.gd")
+	stdout, stderr, err := executeCommandContext(ctx, &projectDir, env, godotPath, a)
+	if err != nil {
+		return "", fmt.Errorf("%v %s", err, stderr)
+	}
+	for _, line := range strings.Split(stdout, "\n") {
+		if adapter, ok := strings.CutPrefix(strings.TrimSpace(line), "VRT_VIDEO_ADAPTER "); ok {
+			return adapter, nil
+		}
+	}
+	return "", fmt.Errorf("godot didn't report a video adapter: %s", stderr)
+}
@@ -0,0 +1,11 @@
+package lib
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// killWithParent makes sure that the command doesn't outlive godot-vrt, even if godot-vrt exits without stopping it.
+func killWithParent(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Pdeathsig: syscall.SIGKILL}
+}
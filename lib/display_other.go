@@ -0,0 +1,8 @@
+//go:build !linux
+
+package lib
+
+import "os/exec"
+
+// killWithParent is only supported on Linux, where virtual displays are most useful anyway.
+func killWithParent(cmd *exec.Cmd) {}
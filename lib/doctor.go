@@ -0,0 +1,221 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+type CheckStatus int
+
+const (
+	CheckOK CheckStatus = iota
+	CheckWarning
+	CheckFailed
+)
+
+// Check is the result of a single diagnostic of the doctor command.
+type Check struct {
+	Name   string
+	Status CheckStatus
+	Detail string
+	// Fix suggests how to solve the problem if the check didn't pass.
+	Fix string
+}
+
+func (c Check) String() string {
+	icon := map[CheckStatus]string{CheckOK: "✅", CheckWarning: "⚠️", CheckFailed: "❌"}[c.Status]
+	s := fmt.Sprintf("%s %s: %s", icon, c.Name, c.Detail)
+	if c.Status != CheckOK && c.Fix != "" {
+		s += "\n   fix: " + c.Fix
+	}
+	return s
+}
+
+// lowDiskSpace is the free disk space below which the doctor warns. Extracted frames take a lot of space.
+const lowDiskSpace = 1 << 30
+
+// ffmpeg features that godot-vrt uses to compare and encode renders.
+var (
+	requiredFFmpegFilters  = []string{"blend", "hstack", "select"}
+	requiredFFmpegEncoders = []string{"mjpeg", "mpeg4", "png"}
+	requiredFFmpegDecoders = []string{"mjpeg", "png"}
+)
+
+// CheckGodot checks the version of Godot. Unsupported versions are only a warning if allowUnsupported is true.
+func CheckGodot(godotPath string, allowUnsupported bool) Check {
+	check := Check{Name: "Godot"}
+	if err := VerifyBinary(godotPath); err != nil {
+		check.Status, check.Detail = CheckFailed, err.Error()
+		check.Fix = "install Godot and pass the path to its executable with --godot"
+		return check
+	}
+	versionResult, err := godotVersion(godotPath)
+	if err != nil {
+		check.Status, check.Detail = CheckFailed, err.Error()
+		check.Fix = "make sure that --godot points to a Godot 4 executable"
+		return check
+	}
+	version, err := ParseGodotVersion(versionResult)
+	if err != nil {
+		check.Status, check.Detail = CheckFailed, err.Error()
+		check.Fix = "make sure that --godot points to a Godot 4 executable"
+		return check
+	}
+	flavour := "standard"
+	if version.Mono {
+		flavour = "mono"
+	}
+	check.Detail = fmt.Sprintf("%s %s (%s, %s build %s)", version.Number(), version.Status, flavour, version.Build, version.Hash)
+	if !version.Supported() {
+		check.Status = CheckFailed
+		if allowUnsupported {
+			check.Status = CheckWarning
+		}
+		check.Detail += " is not supported"
+		check.Fix = fmt.Sprintf("install a stable version in one of the ranges %v, or pass --allow-unsupported-godot to use this one anyway", supportedGodotVersions)
+	}
+	return check
+}
+
+func CheckFFmpeg() []Check {
+	check := Check{Name: "ffmpeg"}
+	if err := VerifyBinary("ffmpeg"); err != nil {
+		check.Status, check.Detail = CheckFailed, err.Error()
+		check.Fix = "install ffmpeg (e.g. brew install ffmpeg or apt install ffmpeg) and add it to the PATH"
+		return []Check{check}
+	}
+	stdout, stderr, err := executeCommandUnsafe(nil, "ffmpeg", []string{"-hide_banner", "-version"})
+	if err != nil {
+		check.Status, check.Detail = CheckFailed, fmt.Sprintf("error executing ffmpeg: %v %s", err, stderr)
+		check.Fix = "reinstall ffmpeg"
+		return []Check{check}
+	}
+	check.Detail, _, _ = strings.Cut(strings.TrimSpace(stdout), "\n")
+	checks := []Check{check}
+
+	for _, feature := range []struct {
+		name     string
+		flag     string
+		required []string
+	}{
+		{"ffmpeg filters", "-filters", requiredFFmpegFilters},
+		{"ffmpeg encoders", "-encoders", requiredFFmpegEncoders},
+		{"ffmpeg decoders", "-decoders", requiredFFmpegDecoders},
+	} {
+		check := Check{Name: feature.name, Detail: strings.Join(feature.required, ", ")}
+		available, err := ffmpegList(feature.flag)
+		if err != nil {
+			check.Status, check.Detail = CheckFailed, err.Error()
+			check.Fix = "reinstall ffmpeg"
+			checks = append(checks, check)
+			continue
+		}
+		var missing []string
+		for _, name := range feature.required {
+			if !slices.Contains(available, name) {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			check.Status, check.Detail = CheckFailed, "missing "+strings.Join(missing, ", ")
+			check.Fix = "install an ffmpeg build with these " + strings.TrimPrefix(feature.name, "ffmpeg ")
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// ffmpegList returns the names that ffmpeg lists with -filters, -encoders or -decoders.
+func ffmpegList(flag string) ([]string, error) {
+	stdout, stderr, err := executeCommandUnsafe(nil, "ffmpeg", []string{"-hide_banner", flag})
+	if err != nil {
+		return nil, fmt.Errorf("error executing ffmpeg %s: %v %s", flag, err, stderr)
+	}
+	// Each entry is a line of capability flags followed by the name, e.g. " V....D mjpeg  Motion JPEG".
+	var names []string
+	for _, line := range strings.Split(stdout, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 {
+			names = append(names, fields[1])
+		}
+	}
+	return names, nil
+}
+
+// CheckDisplay checks that Godot can render, on the virtual display if display isn't nil.
+func CheckDisplay(godotPath string, display *VirtualDisplay) Check {
+	check := Check{Name: "Display"}
+	if display != nil {
+		adapter, err := VerifyDisplay(godotPath, display)
+		if err != nil {
+			check.Status, check.Detail = CheckFailed, err.Error()
+			check.Fix = "install Mesa's OpenGL drivers (e.g. apt install libgl1-mesa-dri) for software rendering"
+			return check
+		}
+		check.Detail = fmt.Sprintf("virtual display %s, rendering with %s", display.Display, adapter)
+		return check
+	}
+
+	if runtime.GOOS == "linux" && os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+		check.Status, check.Detail = CheckFailed, "no display found (neither DISPLAY nor WAYLAND_DISPLAY is set)"
+		check.Fix = "run with --virtual-display"
+		if VerifyBinary("Xvfb") != nil {
+			check.Fix += " after installing Xvfb (e.g. apt install xvfb)"
+		}
+		return check
+	}
+	adapter, err := VideoAdapter(godotPath, nil, nil)
+	if err != nil {
+		check.Status, check.Detail = CheckFailed, "godot can't render: "+err.Error()
+		check.Fix = "install the graphics drivers of your GPU, or run with --virtual-display to render in software"
+		return check
+	}
+	check.Detail = "rendering with " + adapter
+	return check
+}
+
+// errDiskSpaceUnsupported is returned by FreeDiskSpace on systems where it isn't implemented.
+var errDiskSpaceUnsupported = errors.New("free disk space is not supported on this system")
+
+// CheckDiskSpace checks that there's enough space for renders and extracted frames in dir.
+func CheckDiskSpace(dir string) Check {
+	check := Check{Name: "Disk space"}
+	free, err := FreeDiskSpace(dir)
+	if errors.Is(err, errDiskSpaceUnsupported) {
+		check.Detail = "the free disk space can't be checked on this system"
+		return check
+	}
+	if err != nil {
+		check.Status, check.Detail = CheckWarning, fmt.Sprintf("can't determine the free disk space of %s: %v", dir, err)
+		return check
+	}
+	check.Detail = fmt.Sprintf("%.1f GiB free in %s", float64(free)/(1<<30), dir)
+	if free < lowDiskSpace {
+		check.Status = CheckWarning
+		check.Fix = "free up disk space, or run godot-vrt from a directory on a larger disk (temp files are written to the working directory)"
+	}
+	return check
+}
+
+// CheckProjectRenderer reports the project's rendering method and window size.
+func CheckProjectRenderer(projectPath string, virtualDisplay bool) Check {
+	check := Check{Name: "Project renderer"}
+	settings, err := ReadProjectSettings(projectPath)
+	if err != nil {
+		check.Status, check.Detail = CheckFailed, fmt.Sprintf("error reading project settings: %v", err)
+		check.Fix = "pass the directory that contains project.godot with --project"
+		return check
+	}
+	method := projectRenderer(settings)
+	check.Detail = fmt.Sprintf("%s, %s", method, projectResolution(settings))
+	if virtualDisplay && method != "gl_compatibility" {
+		check.Status = CheckWarning
+		check.Detail += " (--virtual-display renders with gl_compatibility instead)"
+		check.Fix = "create baselines with --virtual-display as well, or switch the project to the Compatibility renderer"
+	}
+	return check
+}
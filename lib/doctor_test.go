@@ -0,0 +1,41 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckProjectRenderer(t *testing.T) {
+	project := t.TempDir()
+	content := "config_version=5\n\n[display]\n\nwindow/size/viewport_width=640\nwindow/size/viewport_height=480\n"
+	if err := os.WriteFile(filepath.Join(project, "project.godot"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	check := CheckProjectRenderer(project, false)
+	if check.Status != CheckOK || check.Detail != "forward_plus, 640x480" {
+		t.Errorf("check = %+v, want forward_plus, 640x480", check)
+	}
+	check = CheckProjectRenderer(project, true)
+	if check.Status != CheckWarning || check.Fix == "" {
+		t.Errorf("expected a warning with a fix for forward_plus on a virtual display, got %+v", check)
+	}
+	check = CheckProjectRenderer(t.TempDir(), false)
+	if check.Status != CheckFailed {
+		t.Errorf("expected a failed check without project.godot, got %+v", check)
+	}
+}
+
+func TestCheckString(t *testing.T) {
+	check := Check{Name: "ffmpeg", Status: CheckFailed, Detail: "cannot find binary at ffmpeg", Fix: "install ffmpeg"}
+	want := "❌ ffmpeg: cannot find binary at ffmpeg\n   fix: install ffmpeg"
+	if check.String() != want {
+		t.Errorf("String() = %q, want %q", check.String(), want)
+	}
+	check.Status = CheckOK
+	if strings.Contains(check.String(), "fix:") {
+		t.Errorf("passing checks shouldn't suggest a fix: %q", check.String())
+	}
+}
@@ -0,0 +1,37 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCopyPath(t *testing.T) {
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "menu.checkpoints"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "menu.checkpoints", "start.png"), []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "menu.avi"), []byte("avi"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(t.TempDir(), "replaced", "vrt")
+	if err := CopyPath(filepath.Join(src, "menu.avi"), filepath.Join(dst, "menu.avi")); err != nil {
+		t.Fatal(err)
+	}
+	if err := CopyPath(filepath.Join(src, "menu.checkpoints"), filepath.Join(dst, "menu.checkpoints")); err != nil {
+		t.Fatal(err)
+	}
+	for file, want := range map[string]string{"menu.avi": "avi", "menu.checkpoints/start.png": "png"} {
+		content, err := os.ReadFile(filepath.Join(dst, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != want {
+			t.Errorf("%s = %q, want %q", file, content, want)
+		}
+	}
+}
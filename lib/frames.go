@@ -0,0 +1,79 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FrameRange is an inclusive range of frame numbers. Frames are counted from 0.
+type FrameRange struct {
+	From int
+	To   int
+}
+
+// UnmarshalJSON accepts a single frame (55 or "55") or a range ("10-40").
+func (r *FrameRange) UnmarshalJSON(data []byte) error {
+	var frame int
+	if err := json.Unmarshal(data, &frame); err == nil {
+		*r = FrameRange{From: frame, To: frame}
+		return r.validate()
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("invalid frame range %s: use a frame number or a range like \"10-40\"", data)
+	}
+	parsed, err := ParseFrameRange(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+func ParseFrameRange(s string) (FrameRange, error) {
+	from, to, isRange := strings.Cut(strings.TrimSpace(s), "-")
+	if !isRange {
+		to = from
+	}
+	var r FrameRange
+	var err error
+	if r.From, err = strconv.Atoi(strings.TrimSpace(from)); err != nil {
+		return r, fmt.Errorf("invalid frame range %q: use a frame number or a range like \"10-40\"", s)
+	}
+	if r.To, err = strconv.Atoi(strings.TrimSpace(to)); err != nil {
+		return r, fmt.Errorf("invalid frame range %q: use a frame number or a range like \"10-40\"", s)
+	}
+	return r, r.validate()
+}
+
+func (r FrameRange) validate() error {
+	if r.From < 0 || r.To < r.From {
+		return fmt.Errorf("invalid frame range %d-%d", r.From, r.To)
+	}
+	return nil
+}
+
+// FrameSelection selects the frames of a video that are compared. The zero value selects all frames.
+type FrameSelection struct {
+	// Skip ignores the first frames, e.g. because of shader compilation hitches or fade-ins.
+	Skip int
+	// Ranges limit the comparison to these frames if there are any.
+	Ranges []FrameRange
+}
+
+func (s FrameSelection) Includes(frame int) bool {
+	if frame < s.Skip {
+		return false
+	}
+	if len(s.Ranges) == 0 {
+		return true
+	}
+	for _, r := range s.Ranges {
+		if frame >= r.From && frame <= r.To {
+			return true
+		}
+	}
+	return false
+}
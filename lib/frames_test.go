@@ -0,0 +1,70 @@
+package lib
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestFrameRangeUnmarshalJSON(t *testing.T) {
+	var got []FrameRange
+	if err := json.Unmarshal([]byte(`[55, "10-40", "7", " 1 - 2 "]`), &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	want := []FrameRange{{55, 55}, {10, 40}, {7, 7}, {1, 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal() got %v, want %v", got, want)
+	}
+
+	for _, invalid := range []string{`["40-10"]`, `["a-b"]`, `[-1]`, `[true]`} {
+		if err := json.Unmarshal([]byte(invalid), &got); err == nil {
+			t.Errorf("Unmarshal(%s) expected an error", invalid)
+		}
+	}
+}
+
+func TestFrameSelectionIncludes(t *testing.T) {
+	tests := []struct {
+		name      string
+		selection FrameSelection
+		included  []int
+		excluded  []int
+	}{
+		{
+			name:     "all frames",
+			included: []int{0, 1, 59},
+		},
+		{
+			name:      "skipped frames",
+			selection: FrameSelection{Skip: 10},
+			included:  []int{10, 59},
+			excluded:  []int{0, 9},
+		},
+		{
+			name:      "ranges",
+			selection: FrameSelection{Ranges: []FrameRange{{10, 40}, {55, 55}}},
+			included:  []int{10, 40, 55},
+			excluded:  []int{9, 41, 54, 56},
+		},
+		{
+			name:      "ranges and skipped frames",
+			selection: FrameSelection{Skip: 20, Ranges: []FrameRange{{10, 40}}},
+			included:  []int{20, 40},
+			excluded:  []int{10, 19},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, f := range tt.included {
+				if !tt.selection.Includes(f) {
+					t.Errorf("Includes(%d) = false, want true", f)
+				}
+			}
+			for _, f := range tt.excluded {
+				if tt.selection.Includes(f) {
+					t.Errorf("Includes(%d) = true, want false", f)
+				}
+			}
+		})
+	}
+}
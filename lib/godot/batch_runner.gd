@@ -0,0 +1,52 @@
+extends Node
+
+# Injected by godot-vrt to render many scenes in a single Godot process.
+# It reads a manifest with the scenes to render, saves a fixed number of frames per scene as PNG files,
+# and prints markers to stdout and stderr so that godot-vrt can split Godot's output per scene.
+
+
+func _ready() -> void:
+	var manifest_path := ""
+	for arg in OS.get_cmdline_user_args():
+		if arg.begins_with("--vrt-manifest="):
+			manifest_path = arg.trim_prefix("--vrt-manifest=")
+
+	var manifest = JSON.parse_string(FileAccess.get_file_as_string(manifest_path))
+	if manifest == null:
+		printerr("VRT_BATCH_ERROR cannot read manifest ", manifest_path)
+		get_tree().quit(1)
+		return
+
+	for entry in manifest["scenes"]:
+		await _render(entry, int(entry["frames"]))
+	get_tree().quit()
+
+
+func _render(entry: Dictionary, frames: int) -> void:
+	_marker("VRT_SCENE_START", entry["name"])
+	var packed = load(entry["scene"])
+	if not packed is PackedScene:
+		_marker("VRT_SCENE_ERROR", entry["name"])
+		return
+
+	# The VRT autoload is only available with --checkpoints.
+	var vrt := get_node_or_null("/root/VRT")
+	if vrt:
+		vrt.output_dir = entry.get("checkpoints_dir", "")
+
+	var scene: Node = packed.instantiate()
+	get_tree().root.add_child(scene)
+	get_tree().current_scene = scene
+	for i in frames:
+		await RenderingServer.frame_post_draw
+		var image := get_viewport().get_texture().get_image()
+		image.save_png("%s/frame_%05d.png" % [entry["output_dir"], i + 1])
+
+	scene.queue_free()
+	await get_tree().process_frame
+	_marker("VRT_SCENE_END", entry["name"])
+
+
+func _marker(marker: String, scene_name: String) -> void:
+	print(marker, " ", scene_name)
+	printerr(marker, " ", scene_name)
@@ -0,0 +1,22 @@
+extends Node
+
+# Injected by godot-vrt as the VRT autoload while rendering with --checkpoints.
+# Call VRT.checkpoint("after_door_opens") to save a screenshot that godot-vrt compares against a baseline.
+# The screenshot is taken after the current frame has been drawn, so use `await` if the scene should wait for it.
+
+var output_dir := ""
+
+
+func _ready() -> void:
+	for arg in OS.get_cmdline_user_args():
+		if arg.begins_with("--vrt-checkpoints="):
+			output_dir = arg.trim_prefix("--vrt-checkpoints=")
+
+
+func checkpoint(checkpoint_name: String) -> void:
+	if output_dir == "":
+		return
+	await RenderingServer.frame_post_draw
+	var image := get_viewport().get_texture().get_image()
+	image.save_png("%s/%s.png" % [output_dir, checkpoint_name.validate_filename()])
+	print("VRT_CHECKPOINT ", checkpoint_name)
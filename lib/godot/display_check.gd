@@ -0,0 +1,8 @@
+extends SceneTree
+
+# Run by godot-vrt with -s to check that Godot can create a window and a renderer on the virtual display.
+
+
+func _initialize() -> void:
+	print("VRT_VIDEO_ADAPTER ", RenderingServer.get_video_adapter_name())
+	quit()
@@ -0,0 +1,37 @@
+package lib
+
+import (
+	"strings"
+)
+
+// Godot prefixes errors and warnings with these markers, e.g. "SCRIPT ERROR: Invalid call." or "WARNING: ...".
+var godotErrorPrefixes = []string{"SCRIPT ERROR:", "USER ERROR:", "ERROR:"}
+var godotWarningPrefixes = []string{"SCRIPT WARNING:", "USER WARNING:", "WARNING:"}
+
+type GodotLogScan struct {
+	Errors   []string
+	Warnings []string
+}
+
+// ScanGodotLog collects the error and warning lines that Godot printed while rendering a scene.
+func ScanGodotLog(stdout, stderr string) GodotLogScan {
+	var scan GodotLogScan
+	for _, line := range strings.Split(stdout+"\n"+stderr, "\n") {
+		line = strings.TrimSpace(line)
+		if hasAnyPrefix(line, godotErrorPrefixes) {
+			scan.Errors = append(scan.Errors, line)
+		} else if hasAnyPrefix(line, godotWarningPrefixes) {
+			scan.Warnings = append(scan.Warnings, line)
+		}
+	}
+	return scan
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
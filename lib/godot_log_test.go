@@ -0,0 +1,37 @@
+package lib
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestScanGodotLog(t *testing.T) {
+	stdout := `Godot Engine v4.4.1.stable.official.49a5bc7b6 - https://godotengine.org
+Vulkan 1.3.280 - Forward+ - Using Device #0: NVIDIA - GeForce RTX 3070
+
+SCRIPT ERROR: Invalid call. Nonexistent function 'open_door' in base 'Node2D'.
+          at: _ready (res://door.gd:4)
+player ready
+`
+	stderr := `WARNING: Texture not found: res://missing.png
+     at: load (core/io/resource_loader.cpp:288)
+ERROR: Failed loading resource: res://missing.png.
+USER WARNING: deprecated signal
+`
+	got := ScanGodotLog(stdout, stderr)
+
+	wantErrors := []string{
+		"SCRIPT ERROR: Invalid call. Nonexistent function 'open_door' in base 'Node2D'.",
+		"ERROR: Failed loading resource: res://missing.png.",
+	}
+	wantWarnings := []string{
+		"WARNING: Texture not found: res://missing.png",
+		"USER WARNING: deprecated signal",
+	}
+	if !slices.Equal(got.Errors, wantErrors) {
+		t.Errorf("ScanGodotLog() errors = %v, want %v", got.Errors, wantErrors)
+	}
+	if !slices.Equal(got.Warnings, wantWarnings) {
+		t.Errorf("ScanGodotLog() warnings = %v, want %v", got.Warnings, wantWarnings)
+	}
+}
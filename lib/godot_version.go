@@ -0,0 +1,118 @@
+package lib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GodotVersion is the parsed output of godot --version, e.g. 4.4.1.stable.mono.official.49a5bc7b6.
+type GodotVersion struct {
+	Major int `json:"major"`
+	Minor int `json:"minor"`
+	Patch int `json:"patch"`
+	// Status is stable for releases, or e.g. rc2, beta1 or dev3 for pre-releases.
+	Status string `json:"status"`
+	// Mono is true for the .NET flavour of Godot.
+	Mono bool `json:"mono"`
+	// Build is official for the builds of godotengine.org, and e.g. custom_build for engines built from source.
+	Build string `json:"build"`
+	// Hash is the abbreviated commit hash that the engine was built from.
+	Hash string `json:"hash"`
+	// Full is the unparsed version string.
+	Full string `json:"full"`
+}
+
+// ParseGodotVersion parses the output of godot --version. Only major and minor are required.
+func ParseGodotVersion(version string) (GodotVersion, error) {
+	v := GodotVersion{Full: strings.TrimSpace(version)}
+	parts := strings.Split(v.Full, ".")
+
+	// The version numbers come first: 4.3 or 4.4.1
+	var numbers []int
+	for len(parts) > 0 && len(numbers) < 3 {
+		n, err := strconv.Atoi(parts[0])
+		if err != nil {
+			break
+		}
+		numbers = append(numbers, n)
+		parts = parts[1:]
+	}
+	if len(numbers) < 2 {
+		return v, fmt.Errorf("can't parse godot version %q", v.Full)
+	}
+	v.Major, v.Minor = numbers[0], numbers[1]
+	if len(numbers) == 3 {
+		v.Patch = numbers[2]
+	}
+
+	// followed by the status, the flavour, the build and the commit hash: stable.mono.official.49a5bc7b6
+	if len(parts) > 0 {
+		v.Status, parts = parts[0], parts[1:]
+	}
+	if len(parts) > 0 && parts[0] == "mono" {
+		v.Mono, parts = true, parts[1:]
+	}
+	if len(parts) > 0 {
+		v.Build, parts = parts[0], parts[1:]
+	}
+	if len(parts) > 0 {
+		v.Hash = strings.Join(parts, ".")
+	}
+	return v, nil
+}
+
+// Number returns the version number without status and build, e.g. 4.4.1 (or 4.3 if there is no patch version).
+func (v GodotVersion) Number() string {
+	if v.Patch == 0 {
+		return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+	}
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Compare compares the version numbers of v and other, and returns -1, 0 or +1 like cmp.Compare.
+func (v GodotVersion) Compare(other GodotVersion) int {
+	for _, d := range [][2]int{{v.Major, other.Major}, {v.Minor, other.Minor}, {v.Patch, other.Patch}} {
+		if d[0] != d[1] {
+			if d[0] < d[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// GodotVersionRange includes the versions from Min up to, but excluding, Max.
+type GodotVersionRange struct {
+	Min GodotVersion
+	Max GodotVersion
+}
+
+func (r GodotVersionRange) Contains(v GodotVersion) bool {
+	return v.Compare(r.Min) >= 0 && v.Compare(r.Max) < 0
+}
+
+func (r GodotVersionRange) String() string {
+	return fmt.Sprintf(">=%s <%s", r.Min.Number(), r.Max.Number())
+}
+
+// supportedGodotVersions are the stable versions that godot-vrt is tested with.
+var supportedGodotVersions = []GodotVersionRange{
+	{Min: GodotVersion{Major: 4, Minor: 1, Patch: 4}, Max: GodotVersion{Major: 4, Minor: 2}},
+	{Min: GodotVersion{Major: 4, Minor: 2, Patch: 2}, Max: GodotVersion{Major: 4, Minor: 3}},
+	{Min: GodotVersion{Major: 4, Minor: 3}, Max: GodotVersion{Major: 4, Minor: 5}},
+}
+
+// Supported reports whether v is a stable release in one of the supported version ranges.
+func (v GodotVersion) Supported() bool {
+	if v.Status != "stable" {
+		return false
+	}
+	for _, r := range supportedGodotVersions {
+		if r.Contains(v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package lib
+
+import "testing"
+
+func TestParseGodotVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    GodotVersion
+	}{
+		{"4.4.1.stable.official.49a5bc7b6", GodotVersion{Major: 4, Minor: 4, Patch: 1, Status: "stable", Build: "official", Hash: "49a5bc7b6"}},
+		{"4.3.stable.mono.official.77dcf97d8\n", GodotVersion{Major: 4, Minor: 3, Status: "stable", Mono: true, Build: "official", Hash: "77dcf97d8"}},
+		{"4.5.rc2.official.8a3f6e9c1", GodotVersion{Major: 4, Minor: 5, Status: "rc2", Build: "official", Hash: "8a3f6e9c1"}},
+		{"4.4.stable.custom_build.abc1234", GodotVersion{Major: 4, Minor: 4, Status: "stable", Build: "custom_build", Hash: "abc1234"}},
+		{"4.2.2.stable.arch_linux", GodotVersion{Major: 4, Minor: 2, Patch: 2, Status: "stable", Build: "arch_linux"}},
+		{"4.1", GodotVersion{Major: 4, Minor: 1}},
+	}
+	for _, tt := range tests {
+		got, err := ParseGodotVersion(tt.version)
+		if err != nil {
+			t.Fatalf("ParseGodotVersion(%q) error = %v", tt.version, err)
+		}
+		got.Full = ""
+		if got != tt.want {
+			t.Errorf("ParseGodotVersion(%q) = %+v, want %+v", tt.version, got, tt.want)
+		}
+	}
+
+	for _, version := range []string{"", "Godot Engine", "4"} {
+		if _, err := ParseGodotVersion(version); err == nil {
+			t.Errorf("ParseGodotVersion(%q) expected an error", version)
+		}
+	}
+}
+
+func TestGodotVersionSupported(t *testing.T) {
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{"4.4.1.stable.official.49a5bc7b6", true},
+		{"4.4.stable.mono.official.4c311cbee", true},
+		{"4.3.stable.official.77dcf97d8", true},
+		{"4.2.2.stable.official.15073afe3", true},
+		{"4.2.1.stable.official.b09f793f5", false},
+		{"4.1.4.stable.official.fe0e8e557", true},
+		{"4.1.3.stable.official.f06b6836a", false},
+		{"4.4.1.rc1.official.9f5d0f2d2", false},
+		{"4.5.stable.official.876b29033", false},
+		{"3.6.stable.official.de2f0f147", false},
+	}
+	for _, tt := range tests {
+		v, err := ParseGodotVersion(tt.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := v.Supported(); got != tt.want {
+			t.Errorf("%s Supported() = %v, want %v", tt.version, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Godot prints these lines at startup. They depend on the machine, not on the scene.
+var godotBannerPrefixes = []string{"Godot Engine v", "Vulkan ", "OpenGL API ", "Metal ", "D3D12 ", "Movie Maker mode"}
+
+// Godot's movie writer prints a report when it's done recording, which contains the movie's path (that differs
+// between baseline and test) and timings (that differ between runs).
+var movieWriterReport = []*regexp.Regexp{
+	regexp.MustCompile(`^Done recording movie at path: `),
+	regexp.MustCompile(`^\d+ frames at \d+ FPS \(movie length: .*\), recorded in .* of real-time speed\)\.$`),
+	regexp.MustCompile(`^(CPU|GPU) time: [\d.]+ seconds \(average: [\d.]+ ms/frame\)$`),
+}
+
+var logNormalizers = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?`), "<datetime>"},
+	{regexp.MustCompile(`\d{2}:\d{2}:\d{2}(\.\d+)?`), "<time>"},
+	{regexp.MustCompile(`0x[0-9a-fA-F]+`), "<address>"},
+	// Object ids such as <Node2D#28521729131>
+	{regexp.MustCompile(`#-?\d{6,}`), "#<id>"},
+	// Temp dirs that godot-vrt creates in the project, such as res://.vrt_variant_3129480221/
+	{regexp.MustCompile(`(\.vrt_(?:[a-z]+_)?)\d+`), "${1}<tmp>"},
+}
+
+// NormalizeGodotLog removes Godot's startup banner, the movie writer's report and empty lines from stdout, and replaces values that change
+// from run to run (timestamps, memory addresses, object ids and the project's absolute path) with placeholders.
+func NormalizeGodotLog(stdout, projectPath string) string {
+	absProjectPath, err := filepath.Abs(projectPath)
+	if err != nil {
+		absProjectPath = projectPath
+	}
+
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(stdout, "\r\n", "\n"), "\n") {
+		line = strings.TrimRight(line, " \t")
+		if line == "" || hasAnyPrefix(line, godotBannerPrefixes) || isMovieWriterReport(line) {
+			continue
+		}
+		line = strings.ReplaceAll(line, WithFolderSuffix(filepath.ToSlash(absProjectPath)), "<project>/")
+		for _, n := range logNormalizers {
+			line = n.pattern.ReplaceAllString(line, n.replacement)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// UnifiedDiff returns a unified diff of two texts with three lines of context, or an empty string if they're equal.
+func UnifiedDiff(a, b, fromName, toName string) string {
+	if a == b {
+		return ""
+	}
+	aLines := splitLines(a)
+	bLines := splitLines(b)
+	ops := diffLines(aLines, bLines)
+
+	const context = 3
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", fromName, toName))
+
+	for start := 0; start < len(ops); {
+		// find the next change
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		// extend the hunk until there are more than 2*context unchanged lines in a row
+		end := start
+		for i := start; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				end = i + 1
+			} else if i-end >= 2*context {
+				break
+			}
+		}
+		from := max(start-context, 0)
+		to := min(end+context, len(ops))
+
+		aStart, bStart, aCount, bCount := 0, 0, 0, 0
+		for _, op := range ops[:from] {
+			if op.kind != '+' {
+				aStart++
+			}
+			if op.kind != '-' {
+				bStart++
+			}
+		}
+		for _, op := range ops[from:to] {
+			if op.kind != '+' {
+				aCount++
+			}
+			if op.kind != '-' {
+				bCount++
+			}
+		}
+		sb.WriteString(fmt.Sprintf("@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount)))
+		for _, op := range ops[from:to] {
+			sb.WriteString(fmt.Sprintf("%c%s\n", op.kind, op.line))
+		}
+		start = to
+	}
+	return sb.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// diffLines computes a line diff based on the longest common subsequence. Logs are short enough for the quadratic approach.
+func diffLines(a, b []string) []diffOp {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// WriteGoldenLog saves a normalized log next to a baseline.
+func WriteGoldenLog(file, normalizedLog string) error {
+	if err := os.WriteFile(file, []byte(normalizedLog), 0644); err != nil {
+		return fmt.Errorf("error writing golden log %s: %v", file, err)
+	}
+	return nil
+}
+
+func isMovieWriterReport(line string) bool {
+	for _, pattern := range movieWriterReport {
+		if pattern.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package lib
+
+import "testing"
+
+func TestNormalizeGodotLog(t *testing.T) {
+	stdout := "Godot Engine v4.4.1.stable.official.49a5bc7b6 - https://godotengine.org\n" +
+		"Vulkan 1.3.280 - Forward+ - Using Device #0: NVIDIA - GeForce RTX 3070\n" +
+		"\n" +
+		"door closed at 12:03:44.123\n" +
+		"spawned <Node2D#28521729131> at 0x7ffd5a3c\n" +
+		"saved to /home/dev/game/saves/slot1.save   \r\n" +
+		"door opened\n"
+
+	got := NormalizeGodotLog(stdout, "/home/dev/game")
+	want := "door closed at <time>\n" +
+		"spawned <Node2D#<id>> at <address>\n" +
+		"saved to <project>/saves/slot1.save\n" +
+		"door opened\n"
+	if got != want {
+		t.Errorf("NormalizeGodotLog() got\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestNormalizeGodotLogOfMovieRuns(t *testing.T) {
+	// baseline records next to the scene, test into the temp dir, and the timings differ between the runs
+	baselineRun := "Godot Engine v4.4.1.stable.official.49a5bc7b6 - https://godotengine.org\n" +
+		"OpenGL API 3.3.0 NVIDIA 550.120 - Compatibility - Using Device: NVIDIA - NVIDIA GeForce RTX 3070\n" +
+		"Movie Maker mode enabled, recording movie at path: /home/dev/game/vrt/menu.avi\n" +
+		"\n" +
+		"menu opened in res://.vrt_variant_1837465920/menu.tscn\n" +
+		"Done recording movie at path: /home/dev/game/vrt/menu.avi\n" +
+		"\n" +
+		"60 frames at 60 FPS (movie length: 00:00:01), recorded in 00:00:03 (33% of real-time speed).\n" +
+		"CPU time: 1.84 seconds (average: 30.67 ms/frame)\n" +
+		"GPU time: 0.42 seconds (average: 7.00 ms/frame)\n"
+	testRun := "Godot Engine v4.4.1.stable.official.49a5bc7b6 - https://godotengine.org\n" +
+		"OpenGL API 3.3.0 NVIDIA 550.120 - Compatibility - Using Device: NVIDIA - NVIDIA GeForce RTX 3070\n" +
+		"Movie Maker mode enabled, recording movie at path: /home/dev/game/.vrt_2214598371/vrt/menu_actual.avi\n" +
+		"\n" +
+		"menu opened in res://.vrt_variant_409117235/menu.tscn\n" +
+		"Done recording movie at path: /home/dev/game/.vrt_2214598371/vrt/menu_actual.avi\n" +
+		"\n" +
+		"60 frames at 60 FPS (movie length: 00:00:01), recorded in 00:00:02 (50% of real-time speed).\n" +
+		"CPU time: 1.21 seconds (average: 20.17 ms/frame)\n" +
+		"GPU time: 0.39 seconds (average: 6.50 ms/frame)\n"
+
+	baseline := NormalizeGodotLog(baselineRun, "/home/dev/game")
+	test := NormalizeGodotLog(testRun, "/home/dev/game")
+	if baseline != test {
+		t.Errorf("runs normalized differently:\n%s", UnifiedDiff(baseline, test, "baseline", "test"))
+	}
+	if want := "menu opened in res://.vrt_variant_<tmp>/menu.tscn\n"; baseline != want {
+		t.Errorf("NormalizeGodotLog() got\n%q\nwant\n%q", baseline, want)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want string
+	}{
+		{
+			name: "equal",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+			want: "",
+		},
+		{
+			name: "changed line",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			b:    "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			want: "--- golden\n+++ actual\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name: "added line at the end",
+			a:    "1\n",
+			b:    "1\n2\n",
+			want: "--- golden\n+++ actual\n@@ -1 +1,2 @@\n 1\n+2\n",
+		},
+		{
+			name: "empty golden",
+			a:    "",
+			b:    "1\n",
+			want: "--- golden\n+++ actual\n@@ -0,0 +1 @@\n+1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnifiedDiff(tt.a, tt.b, "golden", "actual"); got != tt.want {
+				t.Errorf("UnifiedDiff() got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
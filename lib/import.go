@@ -0,0 +1,139 @@
+package lib
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ImportProject lets Godot import all assets of the project, so that renders don't show missing textures
+// on a fresh checkout without a .godot directory. Godot runs with the same env as the renders, e.g. with the
+// user data dir of an isolated run.
+func ImportProject(ctx context.Context, godotBinary, projectPath string, env []string, verbose bool) error {
+	version, err := godotVersion(godotBinary)
+	if err != nil {
+		return err
+	}
+	// --import was added in Godot 4.3. Older versions import assets when the editor starts.
+	a := []string{"--headless", "--editor", "--quit"}
+	if v, err := ParseGodotVersion(version); err == nil && v.Compare(GodotVersion{Major: 4, Minor: 3}) >= 0 {
+		a = []string{"--headless", "--import"}
+	}
+	if verbose {
+		a = slices.Insert(a, 0, "--verbose")
+	}
+
+	stdout, stderr, err := executeCommandContext(ctx, &projectPath, env, godotBinary, a)
+	if verbose {
+		fmt.Println(stdout)
+		fmt.Println(stderr)
+	}
+	if err != nil {
+		return fmt.Errorf("error importing assets: %v %s", err, stderr)
+	}
+	if scan := ScanGodotLog(stdout, stderr); len(scan.Errors) > 0 {
+		return fmt.Errorf("godot reported errors while importing assets:\n%s", strings.Join(scan.Errors, "\n"))
+	}
+	return nil
+}
+
+// AssetsHash hashes the paths and contents of all files in the project that affect the import, i.e. all files except
+// hidden directories (such as .godot and .git), directories that Godot ignores because of a .gdignore file, videos,
+// godot-vrt's results and the files it saves next to baselines.
+func AssetsHash(projectPath string) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(projectPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(projectPath, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if rel == "." {
+				return nil
+			}
+			if strings.HasPrefix(d.Name(), ".") || d.Name() == "vrt-results" || strings.HasSuffix(d.Name(), ".checkpoints") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, ".gdignore")); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isVrtFile(path) {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		// WalkDir visits files in lexical order, so the hash is stable.
+		h.Write([]byte(filepath.ToSlash(rel) + "\x00"))
+		_, err = io.Copy(h, f)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("error hashing assets: %v", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// RestoreImportCache copies a cached .godot directory into the project. It returns false if there is no cache entry for the hash.
+func RestoreImportCache(cacheDir, hash, projectPath string) (bool, error) {
+	entry := filepath.Join(cacheDir, hash)
+	if _, err := os.Stat(entry); os.IsNotExist(err) {
+		return false, nil
+	}
+	if err := copyDir(entry, filepath.Join(projectPath, ".godot")); err != nil {
+		return false, fmt.Errorf("error restoring import cache: %v", err)
+	}
+	return true, nil
+}
+
+// SaveImportCache copies the project's .godot directory into the cache.
+func SaveImportCache(cacheDir, hash, projectPath string) error {
+	entry := filepath.Join(cacheDir, hash)
+	// Copy to a temporary directory first, so that an interrupted copy doesn't leave a broken cache entry behind.
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return fmt.Errorf("error creating import cache dir: %v", err)
+	}
+	tmp, err := os.MkdirTemp(cacheDir, ".tmp_")
+	if err != nil {
+		return fmt.Errorf("error creating import cache dir: %v", err)
+	}
+	if err := copyDir(filepath.Join(projectPath, ".godot"), tmp); err != nil {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("error saving import cache: %v", err)
+	}
+	if err := os.Rename(tmp, entry); err != nil {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("error saving import cache: %v", err)
+	}
+	return nil
+}
+
+// isVrtFile reports whether a file was saved by godot-vrt rather than being an asset, i.e. a video, metadata, a golden
+// log, a noise map or a screenshot baseline (which has metadata next to it).
+func isVrtFile(path string) bool {
+	for _, suffix := range []string{".avi", ".vrt.json", ".log.golden", ".noise.png"} {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+	if filepath.Ext(path) == ".png" {
+		_, err := os.Stat(strings.TrimSuffix(path, ".png") + ".vrt.json")
+		return err == nil
+	}
+	return false
+}
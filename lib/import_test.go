@@ -0,0 +1,57 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAssetsHash(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hash := func() string {
+		t.Helper()
+		h, err := AssetsHash(dir)
+		if err != nil {
+			t.Fatalf("AssetsHash() error = %v", err)
+		}
+		return h
+	}
+
+	write("project.godot", "config_version=5")
+	write("sprites/player.png", "png")
+	initial := hash()
+
+	write(".godot/imported/player.png-123.ctex", "imported")
+	write("vrt/menu.avi", "baseline")
+	write("vrt-results/vrt/menu.log", "log")
+	if h := hash(); h != initial {
+		t.Error("expected hash to ignore .godot, baselines and results")
+	}
+
+	write("vrt/menu.vrt.json", "{}")
+	write("vrt/menu.log.golden", "log")
+	write("vrt/vrt-images/.gdignore", "")
+	write("vrt/vrt-images/menu.noise.png", "noise")
+	write("vrt/vrt-images/hud.png", "screenshot")
+	write("vrt/door.checkpoints/opened.png", "checkpoint")
+	write("vrt/old.png", "screenshot")
+	write("vrt/old.vrt.json", "{}")
+	write("vrt/hud.noise.png", "noise")
+	if h := hash(); h != initial {
+		t.Error("expected hash to ignore metadata, golden logs, noise maps, screenshots and checkpoints")
+	}
+
+	write("sprites/player.png", "changed png")
+	if h := hash(); h == initial {
+		t.Error("expected hash to change when an asset changes")
+	}
+}
@@ -0,0 +1,79 @@
+package lib
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// These files are copied instead of hardlinked, because Godot or godot-vrt may write to them during a run.
+var isolatedCopiedFiles = []string{"project.godot", "override.cfg"}
+
+// IsolateProject mirrors the project at projectPath into targetDir, so that renders can't modify the user's working tree.
+// Assets are hardlinked where possible to keep this fast. The .godot directory and files that get written to are copied.
+// Version control directories, godot-vrt's temp directories and results are left out.
+func IsolateProject(projectPath, targetDir string) error {
+	projectPath, err := filepath.Abs(projectPath)
+	if err != nil {
+		return fmt.Errorf("error getting absolute path: %v", err)
+	}
+	targetDir, err = filepath.Abs(targetDir)
+	if err != nil {
+		return fmt.Errorf("error getting absolute path: %v", err)
+	}
+
+	err = filepath.WalkDir(projectPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(projectPath, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(targetDir, rel)
+
+		if d.IsDir() {
+			// The target directory may be inside the project, e.g. if godot-vrt runs from the project root.
+			if path == targetDir || d.Name() == ".git" || d.Name() == "vrt-results" || strings.HasPrefix(d.Name(), ".vrt_") {
+				return filepath.SkipDir
+			}
+			if d.Name() == ".godot" && filepath.Dir(path) == projectPath {
+				if err := copyDir(path, target); err != nil {
+					return err
+				}
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(target, 0755)
+		}
+
+		if rel == filepath.Base(rel) && slices.Contains(isolatedCopiedFiles, rel) {
+			return copyFile(path, target)
+		}
+		if err := os.Link(path, target); err != nil {
+			// Hardlinks don't work across file systems.
+			return copyFile(path, target)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error isolating project: %v", err)
+	}
+	return nil
+}
+
+// UserDataEnv returns environment variables that make Godot resolve user:// into dataDir instead of the user's
+// application data (on Linux via XDG_DATA_HOME, on macOS via HOME, and on Windows via APPDATA). The config and cache
+// dirs, where the editor keeps its settings while importing, are redirected as well.
+func UserDataEnv(dataDir string) []string {
+	return []string{
+		"XDG_DATA_HOME=" + dataDir,
+		"XDG_CONFIG_HOME=" + dataDir,
+		"XDG_CACHE_HOME=" + dataDir,
+		"HOME=" + dataDir,
+		"APPDATA=" + dataDir,
+		"LOCALAPPDATA=" + dataDir,
+	}
+}
@@ -0,0 +1,51 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsolateProject(t *testing.T) {
+	project := t.TempDir()
+	for name, content := range map[string]string{
+		"project.godot":                  "config_version=5",
+		"vrt/menu.tscn":                  "[gd_scene format=3]",
+		".godot/imported/icon.ctex":      "imported",
+		".git/HEAD":                      "ref: refs/heads/main",
+		"vrt-results/vrt/menu.avi":       "result",
+		".vrt_123/project/project.godot": "previous run",
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(project, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(project, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// like InitTmpDir when godot-vrt runs from the project root
+	target := filepath.Join(project, ".vrt_456", "project")
+
+	if err := IsolateProject(project, target); err != nil {
+		t.Fatalf("IsolateProject() error = %v", err)
+	}
+
+	for _, name := range []string{"project.godot", "vrt/menu.tscn", ".godot/imported/icon.ctex"} {
+		if _, err := os.Stat(filepath.Join(target, name)); err != nil {
+			t.Errorf("expected %s to be isolated: %v", name, err)
+		}
+	}
+	for _, name := range []string{".git", "vrt-results", ".vrt_123", ".vrt_456"} {
+		if _, err := os.Stat(filepath.Join(target, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be left out", name)
+		}
+	}
+
+	// writing to the isolated project.godot must not change the original
+	if err := os.WriteFile(filepath.Join(target, "project.godot"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(project, "project.godot")); string(content) != "config_version=5" {
+		t.Errorf("original project.godot was modified: %s", content)
+	}
+}
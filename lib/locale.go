@@ -0,0 +1,55 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+)
+
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}([_-][A-Za-z0-9]+)*$`)
+
+func VerifyLocale(locale string) error {
+	if !localePattern.MatchString(locale) {
+		return fmt.Errorf("invalid locale %q: use a locale code such as en, de or pt_BR", locale)
+	}
+	return nil
+}
+
+// InjectLocale forces Godot to use the given locale through the project's override.cfg.
+// The returned function restores the project's original state.
+func InjectLocale(projectPath, locale string) (func(), error) {
+	if err := VerifyLocale(locale); err != nil {
+		return nil, err
+	}
+	return injectOverrideConfig(projectPath, fmt.Sprintf("[internationalization]\n\nlocale/test=\"%s\"\n", locale))
+}
+
+// injectOverrideConfig appends settings to the override.cfg in the project root, which Godot applies on top of
+// project.godot. An existing override.cfg is kept. The returned function restores the previous override.cfg, so
+// nested injections are undone in reverse order.
+func injectOverrideConfig(projectPath, settings string) (func(), error) {
+	overrideFile := WithFolderSuffix(projectPath) + "override.cfg"
+
+	original, err := os.ReadFile(overrideFile)
+	existed := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error reading %s: %v", overrideFile, err)
+	}
+
+	// Godot merges repeated sections, and later keys win.
+	content := string(original) + "\n" + settings
+	if err := os.WriteFile(overrideFile, []byte(content), 0644); err != nil {
+		return nil, fmt.Errorf("error writing %s: %v", overrideFile, err)
+	}
+
+	return func() {
+		if existed {
+			err = os.WriteFile(overrideFile, original, 0644)
+		} else {
+			err = os.Remove(overrideFile)
+		}
+		if err != nil {
+			fmt.Printf("error restoring %s: %v", overrideFile, err)
+		}
+	}, nil
+}
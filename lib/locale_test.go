@@ -0,0 +1,59 @@
+package lib
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestInjectLocale(t *testing.T) {
+	t.Run("creates and removes override.cfg", func(t *testing.T) {
+		dir := t.TempDir()
+
+		restore, err := InjectLocale(dir, "de")
+		if err != nil {
+			t.Fatalf("InjectLocale() error = %v", err)
+		}
+		content, err := os.ReadFile(dir + "/override.cfg")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(content), "locale/test=\"de\"") {
+			t.Errorf("override.cfg does not force the locale: %s", content)
+		}
+
+		restore()
+		if _, err := os.Stat(dir + "/override.cfg"); !os.IsNotExist(err) {
+			t.Error("expected override.cfg to be removed")
+		}
+	})
+
+	t.Run("keeps an existing override.cfg", func(t *testing.T) {
+		dir := t.TempDir()
+		original := "[display]\n\nwindow/size/viewport_width=320\n"
+		if err := os.WriteFile(dir+"/override.cfg", []byte(original), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		restore, err := InjectLocale(dir, "pt_BR")
+		if err != nil {
+			t.Fatalf("InjectLocale() error = %v", err)
+		}
+		content, _ := os.ReadFile(dir + "/override.cfg")
+		if !strings.HasPrefix(string(content), original) || !strings.Contains(string(content), "locale/test=\"pt_BR\"") {
+			t.Errorf("unexpected override.cfg: %s", content)
+		}
+
+		restore()
+		content, _ = os.ReadFile(dir + "/override.cfg")
+		if string(content) != original {
+			t.Errorf("expected original override.cfg to be restored, got %s", content)
+		}
+	})
+
+	t.Run("rejects invalid locales", func(t *testing.T) {
+		if _, err := InjectLocale(t.TempDir(), "de\"\n[application]"); err == nil {
+			t.Error("expected an error for an invalid locale")
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Statuses of a scene in the test manifest.
+const (
+	StatusPassed      = "passed"
+	StatusFailed      = "failed"
+	StatusTimedOut    = "timed-out"
+	StatusFlakyPassed = "flaky-passed"
+)
+
+// Retry policies decide whether a scene that failed passes after it was rendered again.
+const (
+	RetryPolicyAny      = "any"
+	RetryPolicyMajority = "majority"
+)
+
+// Manifest summarizes a test run, so that CI can track scenes over time.
+type Manifest struct {
+	Scenes []ManifestScene `json:"scenes"`
+}
+
+type ManifestScene struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Retries is the number of times the scene was rendered again after it failed, and RetriesPassed how many of
+	// these renders matched the baseline.
+	Retries       int `json:"retries,omitempty"`
+	RetriesPassed int `json:"retries_passed,omitempty"`
+	// Quarantined scenes don't fail the test run, whatever their status.
+	Quarantined bool `json:"quarantined,omitempty"`
+}
+
+// RetriesPass reports whether a failed scene passes, given that passed of its retries matched the baseline.
+func RetriesPass(policy string, passed, retries int) (bool, error) {
+	switch policy {
+	case RetryPolicyAny:
+		return passed > 0, nil
+	case RetryPolicyMajority:
+		return passed > retries/2, nil
+	default:
+		return false, fmt.Errorf("unknown retry policy %q: use %q or %q", policy, RetryPolicyAny, RetryPolicyMajority)
+	}
+}
+
+func WriteManifest(path string, manifest Manifest) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating dir: %v", err)
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding manifest: %v", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing manifest: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package lib
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRetriesPass(t *testing.T) {
+	tests := []struct {
+		policy  string
+		passed  int
+		retries int
+		want    bool
+	}{
+		{RetryPolicyAny, 0, 3, false},
+		{RetryPolicyAny, 1, 3, true},
+		{RetryPolicyMajority, 1, 3, false},
+		{RetryPolicyMajority, 2, 3, true},
+		{RetryPolicyMajority, 1, 2, false},
+		{RetryPolicyMajority, 2, 2, true},
+		{RetryPolicyMajority, 1, 1, true},
+	}
+	for _, tt := range tests {
+		got, err := RetriesPass(tt.policy, tt.passed, tt.retries)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("RetriesPass(%q, %d, %d) = %v, want %v", tt.policy, tt.passed, tt.retries, got, tt.want)
+		}
+	}
+
+	if _, err := RetriesPass("sometimes", 1, 1); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+}
+
+func TestWriteManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vrt-results", "manifest.json")
+	manifest := Manifest{Scenes: []ManifestScene{
+		{Name: "vrt/menu", Status: StatusPassed},
+		{Name: "vrt/particles", Status: StatusFlakyPassed, Retries: 3, RetriesPassed: 2},
+	}}
+	if err := WriteManifest(path, manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Manifest
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Scenes) != 2 || got.Scenes[1] != manifest.Scenes[1] {
+		t.Errorf("manifest = %+v, want %+v", got, manifest)
+	}
+}
@@ -0,0 +1,163 @@
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Environment describes where and how scenes are rendered. Renders of different environments often differ slightly,
+// e.g. because of driver updates or font rendering, so baselines should be rendered in the same environment as tests.
+type Environment struct {
+	Godot GodotVersion `json:"godot"`
+	// Renderer is the rendering method, e.g. forward_plus or gl_compatibility.
+	Renderer string `json:"renderer"`
+	// Resolution is the project's viewport size, e.g. 1152x648.
+	Resolution string `json:"resolution"`
+	// OS is the operating system and architecture, e.g. linux/amd64.
+	OS string `json:"os"`
+	// Encoder is how videos are encoded, EncoderMovieWriter or EncoderBatch. It's empty for screenshots, which are
+	// lossless pngs either way, and for baselines of older versions of godot-vrt.
+	Encoder string `json:"encoder,omitempty"`
+}
+
+// Videos rendered by Godot's movie writer and videos that batch mode encodes from the rendered frames with ffmpeg
+// always differ, even if the frames are the same.
+const (
+	EncoderMovieWriter = "movie_writer"
+	EncoderBatch       = "batch"
+)
+
+// CurrentEnvironment returns the environment that Godot renders the project in. godotArgs are the additional
+// arguments that Godot is started with, which may override the project's renderer.
+func CurrentEnvironment(projectPath string, godot GodotVersion, godotArgs []string) (Environment, error) {
+	settings, err := ReadProjectSettings(projectPath)
+	if err != nil {
+		return Environment{}, fmt.Errorf("error reading project settings: %v", err)
+	}
+	env := Environment{
+		Godot:      godot,
+		Renderer:   projectRenderer(settings),
+		Resolution: projectResolution(settings),
+		OS:         runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if i := slices.Index(godotArgs, "--rendering-method"); i >= 0 && i+1 < len(godotArgs) {
+		env.Renderer = godotArgs[i+1]
+	}
+	return env, nil
+}
+
+func projectRenderer(settings map[string]string) string {
+	if method := settings["rendering/renderer/rendering_method"]; method != "" {
+		return method
+	}
+	return "forward_plus"
+}
+
+func projectResolution(settings map[string]string) string {
+	width, height := settings["display/window/size/viewport_width"], settings["display/window/size/viewport_height"]
+	// Godot doesn't save settings that have their default value.
+	if width == "" {
+		width = "1152"
+	}
+	if height == "" {
+		height = "648"
+	}
+	return width + "x" + height
+}
+
+// Differences describes how e differs from other, e.g. "Godot 4.3 stable instead of 4.4.1 stable".
+func (e Environment) Differences(other Environment) []string {
+	var differences []string
+	if godot, otherGodot := e.Godot.describe(), other.Godot.describe(); godot != otherGodot {
+		differences = append(differences, fmt.Sprintf("Godot %s instead of %s", godot, otherGodot))
+	}
+	if e.Renderer != other.Renderer {
+		differences = append(differences, fmt.Sprintf("renderer %s instead of %s", e.Renderer, other.Renderer))
+	}
+	if e.Resolution != other.Resolution {
+		differences = append(differences, fmt.Sprintf("resolution %s instead of %s", e.Resolution, other.Resolution))
+	}
+	if e.OS != other.OS {
+		differences = append(differences, fmt.Sprintf("OS %s instead of %s", e.OS, other.OS))
+	}
+	if e.Encoder != "" && other.Encoder != "" && e.Encoder != other.Encoder {
+		differences = append(differences, fmt.Sprintf("videos encoded by %s instead of %s (render baselines and tests with the same --batch setting)",
+			e.Encoder, other.Encoder))
+	}
+	return differences
+}
+
+// describe returns the parts of the version that affect renders, e.g. 4.4.1 stable mono (official).
+func (v GodotVersion) describe() string {
+	s := v.Number() + " " + v.Status
+	if v.Mono {
+		s += " mono"
+	}
+	return s + " (" + v.Build + ")"
+}
+
+// BaselineMetadata describes how a baseline was rendered. It's saved next to the baseline as <scene>.vrt.json.
+type BaselineMetadata struct {
+	Environment
+	// Frames is the number of frames that Godot rendered.
+	Frames int           `json:"frames"`
+	Hashes ContentHashes `json:"hashes"`
+}
+
+// ContentHashes are sha256 hashes of the files a baseline was made from and of the baseline itself.
+type ContentHashes struct {
+	Scene    string `json:"scene"`
+	Baseline string `json:"baseline"`
+}
+
+// MetadataFile returns the metadata file of a baseline, e.g. vrt/menu.vrt.json for vrt/menu.avi.
+func MetadataFile(baselineFile string) string {
+	return strings.TrimSuffix(baselineFile, filepath.Ext(baselineFile)) + ".vrt.json"
+}
+
+func WriteMetadata(path string, metadata BaselineMetadata) error {
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding metadata: %v", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing metadata: %v", err)
+	}
+	return nil
+}
+
+// ReadMetadata reads the metadata of a baseline. The returned error satisfies os.IsNotExist if the baseline doesn't
+// have metadata, e.g. because it was rendered by an older version of godot-vrt.
+func ReadMetadata(path string) (BaselineMetadata, error) {
+	var metadata BaselineMetadata
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return metadata, err
+	}
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return metadata, fmt.Errorf("error parsing metadata %s: %v", path, err)
+	}
+	return metadata, nil
+}
+
+// FileHash returns the hex encoded sha256 hash of a file's content.
+func FileHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("error hashing %s: %v", path, err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("error hashing %s: %v", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,96 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestMetadataFile(t *testing.T) {
+	if got := MetadataFile("vrt/menu.de.avi"); got != "vrt/menu.de.vrt.json" {
+		t.Errorf("MetadataFile() = %q, want vrt/menu.de.vrt.json", got)
+	}
+}
+
+func TestWriteAndReadMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "menu.vrt.json")
+	version, err := ParseGodotVersion("4.4.1.stable.mono.official.49a5bc7b6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata := BaselineMetadata{
+		Environment: Environment{Godot: version, Renderer: "forward_plus", Resolution: "1152x648", OS: "linux/amd64"},
+		Frames:      60,
+		Hashes:      ContentHashes{Scene: "abc", Baseline: "def"},
+	}
+	if err := WriteMetadata(path, metadata); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadMetadata(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != metadata {
+		t.Errorf("ReadMetadata() = %+v, want %+v", got, metadata)
+	}
+
+	if _, err := ReadMetadata(filepath.Join(t.TempDir(), "missing.vrt.json")); !os.IsNotExist(err) {
+		t.Errorf("expected a not exist error, got %v", err)
+	}
+}
+
+func TestCurrentEnvironment(t *testing.T) {
+	project := t.TempDir()
+	content := "config_version=5\n\n[display]\n\nwindow/size/viewport_width=640\nwindow/size/viewport_height=480\n"
+	if err := os.WriteFile(filepath.Join(project, "project.godot"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := CurrentEnvironment(project, GodotVersion{Major: 4, Minor: 4}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.Renderer != "forward_plus" || env.Resolution != "640x480" {
+		t.Errorf("env = %+v, want forward_plus and 640x480", env)
+	}
+	env, err = CurrentEnvironment(project, GodotVersion{Major: 4, Minor: 4}, VirtualDisplayGodotArgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.Renderer != "gl_compatibility" {
+		t.Errorf("Renderer = %q, want the renderer of the godot args", env.Renderer)
+	}
+}
+
+func TestEnvironmentDifferences(t *testing.T) {
+	baseline := Environment{
+		Godot:    GodotVersion{Major: 4, Minor: 3, Status: "stable", Build: "official", Hash: "77dcf97d8"},
+		Renderer: "forward_plus", Resolution: "1152x648", OS: "linux/amd64",
+	}
+	if differences := baseline.Differences(baseline); len(differences) != 0 {
+		t.Errorf("expected no differences, got %v", differences)
+	}
+
+	current := baseline
+	current.Godot = GodotVersion{Major: 4, Minor: 4, Patch: 1, Status: "stable", Build: "official", Hash: "49a5bc7b6"}
+	current.OS = "darwin/arm64"
+	want := []string{
+		"Godot 4.4.1 stable (official) instead of 4.3 stable (official)",
+		"OS darwin/arm64 instead of linux/amd64",
+	}
+	if differences := current.Differences(baseline); !slices.Equal(differences, want) {
+		t.Errorf("Differences() = %v, want %v", differences, want)
+	}
+
+	batch := baseline
+	batch.Encoder = EncoderBatch
+	if differences := batch.Differences(baseline); len(differences) != 0 {
+		t.Errorf("expected no differences to a baseline without encoder, got %v", differences)
+	}
+	movieWriter := baseline
+	movieWriter.Encoder = EncoderMovieWriter
+	if differences := batch.Differences(movieWriter); len(differences) != 1 {
+		t.Errorf("expected the encoder to differ, got %v", differences)
+	}
+}
@@ -0,0 +1,256 @@
+package lib
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// MeasureNoise compares several renders of the same scene and returns, per pixel, the largest difference of any
+// colour channel between the renders in any of the selected frames (0-255). Renders are videos, or screenshots if
+// they're .png files. A pixel with a value of 0 rendered the same way every time.
+func MeasureNoise(renders []string, duration int, frames FrameSelection, verbose bool) (*image.Gray, error) {
+	if len(renders) < 2 {
+		return nil, fmt.Errorf("measuring noise requires at least 2 renders, got %d", len(renders))
+	}
+
+	// frameFiles[i] holds the frames of renders[i]
+	frameFiles := make([][]string, len(renders))
+	for i, render := range renders {
+		files, cleanup, err := renderFrames(render, duration, verbose)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		frameFiles[i] = files
+	}
+
+	frameCount := len(frameFiles[0])
+	for _, files := range frameFiles {
+		frameCount = min(frameCount, len(files))
+	}
+
+	var noise *image.Gray
+	for frame := 0; frame < frameCount; frame++ {
+		if len(frameFiles[0]) > 1 && !frames.Includes(frame) {
+			continue
+		}
+		images := make([]image.Image, len(renders))
+		for i := range renders {
+			img, err := decodeImage(frameFiles[i][frame])
+			if err != nil {
+				return nil, err
+			}
+			images[i] = img
+		}
+		bounds := images[0].Bounds()
+		if noise == nil {
+			noise = image.NewGray(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		}
+		for _, img := range images[1:] {
+			if img.Bounds().Dx() != bounds.Dx() || img.Bounds().Dy() != bounds.Dy() {
+				return nil, fmt.Errorf("renders have different sizes: %v and %v", bounds, img.Bounds())
+			}
+		}
+
+		for y := 0; y < bounds.Dy(); y++ {
+			for x := 0; x < bounds.Dx(); x++ {
+				var lo, hi [4]uint32
+				for i, img := range images {
+					r, g, b, a := img.At(x+img.Bounds().Min.X, y+img.Bounds().Min.Y).RGBA()
+					c := [4]uint32{r >> 8, g >> 8, b >> 8, a >> 8}
+					for ch := range c {
+						if i == 0 || c[ch] < lo[ch] {
+							lo[ch] = c[ch]
+						}
+						if i == 0 || c[ch] > hi[ch] {
+							hi[ch] = c[ch]
+						}
+					}
+				}
+				diff := uint8(0)
+				for ch := range lo {
+					diff = max(diff, uint8(hi[ch]-lo[ch]))
+				}
+				if diff > noise.GrayAt(x, y).Y {
+					noise.SetGray(x, y, color.Gray{Y: diff})
+				}
+			}
+		}
+	}
+	if noise == nil {
+		return nil, fmt.Errorf("no frames to measure noise in")
+	}
+	return noise, nil
+}
+
+// ExceedsNoise compares a render against its baseline like HasDiff, but ignores differences of a pixel that are
+// within the pixel's calibrated noise (see MeasureNoise).
+func ExceedsNoise(rendered, baseline string, noise *image.Gray, duration int, frames FrameSelection, verbose bool) (bool, error) {
+	renderedFrames, cleanupRendered, err := renderFrames(rendered, duration, verbose)
+	if err != nil {
+		return false, err
+	}
+	defer cleanupRendered()
+	baselineFrames, cleanupBaseline, err := renderFrames(baseline, duration, verbose)
+	if err != nil {
+		return false, err
+	}
+	defer cleanupBaseline()
+
+	if len(renderedFrames) != len(baselineFrames) {
+		return true, nil
+	}
+	for frame := range renderedFrames {
+		if len(renderedFrames) > 1 && !frames.Includes(frame) {
+			continue
+		}
+		a, err := decodeImage(renderedFrames[frame])
+		if err != nil {
+			return false, err
+		}
+		b, err := decodeImage(baselineFrames[frame])
+		if err != nil {
+			return false, err
+		}
+		if a.Bounds().Dx() != b.Bounds().Dx() || a.Bounds().Dy() != b.Bounds().Dy() {
+			return true, nil
+		}
+		if a.Bounds().Dx() != noise.Bounds().Dx() || a.Bounds().Dy() != noise.Bounds().Dy() {
+			return false, fmt.Errorf("noise map has a different size than the render: %v and %v", noise.Bounds(), a.Bounds())
+		}
+
+		for y := 0; y < a.Bounds().Dy(); y++ {
+			for x := 0; x < a.Bounds().Dx(); x++ {
+				r1, g1, b1, a1 := a.At(x+a.Bounds().Min.X, y+a.Bounds().Min.Y).RGBA()
+				r2, g2, b2, a2 := b.At(x+b.Bounds().Min.X, y+b.Bounds().Min.Y).RGBA()
+				tolerance := uint32(noise.GrayAt(x+noise.Bounds().Min.X, y+noise.Bounds().Min.Y).Y)
+				if absDiff(r1>>8, r2>>8) > tolerance || absDiff(g1>>8, g2>>8) > tolerance ||
+					absDiff(b1>>8, b2>>8) > tolerance || absDiff(a1>>8, a2>>8) > tolerance {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+func absDiff(a, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// renderFrames returns the frames of a render as png files. Screenshots are a single frame already, and videos are
+// extracted into a temporary directory that the returned function removes.
+func renderFrames(render string, duration int, verbose bool) ([]string, func(), error) {
+	if filepath.Ext(render) == ".png" {
+		return []string{render}, func() {}, nil
+	}
+	files, err := ExtractFrames(render, duration, verbose)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(files) == 0 {
+		return nil, nil, fmt.Errorf("no frames extracted from %s", render)
+	}
+	return files, func() { os.RemoveAll(filepath.Dir(files[0])) }, nil
+}
+
+// LoadNoiseMap reads a noise map that was saved with SavePNG.
+func LoadNoiseMap(path string) (*image.Gray, error) {
+	img, err := decodeImage(path)
+	if err != nil {
+		return nil, err
+	}
+	if gray, ok := img.(*image.Gray); ok {
+		return gray, nil
+	}
+	bounds := img.Bounds()
+	gray := image.NewGray(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			gray.Set(x, y, img.At(x+bounds.Min.X, y+bounds.Min.Y))
+		}
+	}
+	return gray, nil
+}
+
+// UnstablePixels counts the pixels of a noise map that changed between renders.
+func UnstablePixels(noise *image.Gray) int {
+	count := 0
+	for _, v := range noise.Pix {
+		if v > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// UnstableRegions returns the bounding boxes of connected areas of unstable pixels, largest first.
+func UnstableRegions(noise *image.Gray) []image.Rectangle {
+	bounds := noise.Bounds()
+	visited := make([]bool, len(noise.Pix))
+	index := func(x, y int) int { return (y-bounds.Min.Y)*bounds.Dx() + (x - bounds.Min.X) }
+
+	var regions []image.Rectangle
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if visited[index(x, y)] || noise.GrayAt(x, y).Y == 0 {
+				continue
+			}
+			// flood fill the region to find its bounding box
+			region := image.Rect(x, y, x+1, y+1)
+			stack := []image.Point{{x, y}}
+			visited[index(x, y)] = true
+			for len(stack) > 0 {
+				p := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				region = region.Union(image.Rect(p.X, p.Y, p.X+1, p.Y+1))
+				for _, n := range []image.Point{{p.X + 1, p.Y}, {p.X - 1, p.Y}, {p.X, p.Y + 1}, {p.X, p.Y - 1}} {
+					if !n.In(bounds) || visited[index(n.X, n.Y)] || noise.GrayAt(n.X, n.Y).Y == 0 {
+						continue
+					}
+					visited[index(n.X, n.Y)] = true
+					stack = append(stack, n)
+				}
+			}
+			regions = append(regions, region)
+		}
+	}
+	slices.SortStableFunc(regions, func(a, b image.Rectangle) int {
+		return b.Dx()*b.Dy() - a.Dx()*a.Dy()
+	})
+	return regions
+}
+
+// IgnoreMask turns a noise map into a mask that is white where pixels are unstable, and black everywhere else.
+func IgnoreMask(noise *image.Gray) *image.Gray {
+	mask := image.NewGray(noise.Bounds())
+	for i, v := range noise.Pix {
+		if v > 0 {
+			mask.Pix[i] = 255
+		}
+	}
+	return mask
+}
+
+func SavePNG(img image.Image, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating dir: %v", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %v", path, err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return fmt.Errorf("error encoding %s: %v", path, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,91 @@
+package lib
+
+import (
+	"image"
+	"image/color"
+	"path/filepath"
+	"testing"
+)
+
+func TestMeasureNoise(t *testing.T) {
+	dir := t.TempDir()
+	black := color.RGBA{A: 255}
+	renders := []string{filepath.Join(dir, "run0.png"), filepath.Join(dir, "run1.png"), filepath.Join(dir, "run2.png")}
+	for i, render := range renders {
+		img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+		for y := 0; y < 8; y++ {
+			for x := 0; x < 8; x++ {
+				img.Set(x, y, black)
+			}
+		}
+		// a flickering 2x2 particle in the top left corner, and a single jittering pixel in the bottom right corner
+		img.Set(1, 1, color.RGBA{R: uint8(100 * i), A: 255})
+		img.Set(2, 1, color.RGBA{G: uint8(10 * i), A: 255})
+		img.Set(1, 2, color.RGBA{B: uint8(20 * i), A: 255})
+		img.Set(2, 2, color.RGBA{R: uint8(30 * i), A: 255})
+		img.Set(7, 7, color.RGBA{R: uint8(i % 2), A: 255})
+		if err := SavePNG(img, render); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	noise, err := MeasureNoise(renders, 1, FrameSelection{}, false)
+	if err != nil {
+		t.Fatalf("MeasureNoise() error = %v", err)
+	}
+	if got := noise.GrayAt(1, 1).Y; got != 200 {
+		t.Errorf("noise at 1,1 = %d, want 200", got)
+	}
+	if got := noise.GrayAt(7, 7).Y; got != 1 {
+		t.Errorf("noise at 7,7 = %d, want 1", got)
+	}
+	if got := noise.GrayAt(0, 0).Y; got != 0 {
+		t.Errorf("noise at 0,0 = %d, want 0", got)
+	}
+	if got := UnstablePixels(noise); got != 5 {
+		t.Errorf("UnstablePixels() = %d, want 5", got)
+	}
+
+	regions := UnstableRegions(noise)
+	want := []image.Rectangle{image.Rect(1, 1, 3, 3), image.Rect(7, 7, 8, 8)}
+	if len(regions) != len(want) || regions[0] != want[0] || regions[1] != want[1] {
+		t.Errorf("UnstableRegions() = %v, want %v", regions, want)
+	}
+
+	mask := IgnoreMask(noise)
+	if mask.GrayAt(7, 7).Y != 255 || mask.GrayAt(0, 0).Y != 0 {
+		t.Error("IgnoreMask() should be white for unstable pixels only")
+	}
+}
+
+func TestExceedsNoise(t *testing.T) {
+	dir := t.TempDir()
+	solid := func(name string, c color.Color) string {
+		img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+		for y := 0; y < 2; y++ {
+			for x := 0; x < 2; x++ {
+				img.Set(x, y, c)
+			}
+		}
+		path := filepath.Join(dir, name)
+		if err := SavePNG(img, path); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	baseline := solid("baseline.png", color.RGBA{R: 100, A: 255})
+	withinNoise := solid("within.png", color.RGBA{R: 110, A: 255})
+	beyondNoise := solid("beyond.png", color.RGBA{R: 111, A: 255})
+
+	noise := image.NewGray(image.Rect(0, 0, 2, 2))
+	for i := range noise.Pix {
+		noise.Pix[i] = 10
+	}
+
+	if got, err := ExceedsNoise(withinNoise, baseline, noise, 1, FrameSelection{}, false); err != nil || got {
+		t.Errorf("ExceedsNoise() = %v, %v, want false", got, err)
+	}
+	if got, err := ExceedsNoise(beyondNoise, baseline, noise, 1, FrameSelection{}, false); err != nil || !got {
+		t.Errorf("ExceedsNoise() = %v, %v, want true", got, err)
+	}
+}
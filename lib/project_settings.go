@@ -0,0 +1,52 @@
+package lib
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// ReadProjectSettings reads the settings of project.godot and override.cfg (which takes precedence) into a map from
+// the setting's path (e.g. rendering/renderer/rendering_method) to its raw value. Strings are unquoted, other values
+// (e.g. Vector2(1, 2) or arrays) are kept as they are written.
+func ReadProjectSettings(projectPath string) (map[string]string, error) {
+	settings := map[string]string{}
+	if err := readSettingsFile(WithFolderSuffix(projectPath)+"project.godot", settings); err != nil {
+		return nil, err
+	}
+	if err := readSettingsFile(WithFolderSuffix(projectPath)+"override.cfg", settings); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func readSettingsFile(path string, settings map[string]string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.Trim(line, "[]")
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		// Lines without = continue multi-line values such as dictionaries, which aren't supported.
+		if !ok || strings.ContainsAny(key, " \"{}") {
+			continue
+		}
+		if section != "" {
+			key = section + "/" + key
+		}
+		settings[key] = strings.Trim(value, "\"")
+	}
+	return scanner.Err()
+}
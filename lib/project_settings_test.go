@@ -0,0 +1,57 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadProjectSettings(t *testing.T) {
+	project := t.TempDir()
+	projectGodot := `; Engine configuration file.
+config_version=5
+
+[application]
+
+config/name="Test Project"
+config/features=PackedStringArray("4.4", "Forward Plus")
+
+[autoload]
+
+Global="*res://global.gd"
+
+[rendering]
+
+renderer/rendering_method="forward_plus"
+`
+	if err := os.WriteFile(filepath.Join(project, "project.godot"), []byte(projectGodot), 0644); err != nil {
+		t.Fatal(err)
+	}
+	override := "[rendering]\n\nrenderer/rendering_method=\"gl_compatibility\"\n"
+	if err := os.WriteFile(filepath.Join(project, "override.cfg"), []byte(override), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	settings, err := ReadProjectSettings(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"config_version":                      "5",
+		"application/config/name":             "Test Project",
+		"application/config/features":         `PackedStringArray("4.4", "Forward Plus")`,
+		"autoload/Global":                     "*res://global.gd",
+		"rendering/renderer/rendering_method": "gl_compatibility",
+	}
+	for key, value := range want {
+		if settings[key] != value {
+			t.Errorf("settings[%q] = %q, want %q", key, settings[key], value)
+		}
+	}
+}
+
+func TestReadProjectSettingsMissingProject(t *testing.T) {
+	if _, err := ReadProjectSettings(t.TempDir()); err == nil {
+		t.Error("expected an error without project.godot")
+	}
+}
@@ -0,0 +1,79 @@
+package lib
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FindTmpDirs lists the temp directories in dir that InitTmpDir created and that weren't removed, e.g. because
+// godot-vrt was killed.
+func FindTmpDirs(dir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, ".vrt_*"))
+	if err != nil {
+		return nil, fmt.Errorf("error listing temp dirs in %s: %v", dir, err)
+	}
+	var dirs []string
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && info.IsDir() {
+			dirs = append(dirs, match)
+		}
+	}
+	return dirs, nil
+}
+
+// FindFrameDirs lists the .frames_ directories below root that ExtractFrames created and that weren't removed.
+// Temp directories and Godot's .godot directory are skipped.
+func FindFrameDirs(root string) ([]string, error) {
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || path == root {
+			return nil
+		}
+		name := d.Name()
+		if strings.HasPrefix(name, ".frames_") {
+			dirs = append(dirs, path)
+			return filepath.SkipDir
+		}
+		if name == ".godot" || strings.HasPrefix(name, ".vrt_") {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing frame dirs in %s: %v", root, err)
+	}
+	return dirs, nil
+}
+
+// FindStaleFiles lists the files below dir that were last modified before the given time. A missing dir has no
+// stale files.
+func FindStaleFiles(dir string, before time.Time) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().Before(before) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error listing files in %s: %v", dir, err)
+	}
+	return files, nil
+}
@@ -0,0 +1,75 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFindTmpAndFrameDirs(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{
+		".vrt_123/.frames_menu_1",
+		".godot/.frames_cache_1",
+		"vrt/.frames_menu_2/nested",
+		"vrt-results/.frames_menu_diff_3",
+	} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a file that looks like a temp dir isn't one
+	if err := os.WriteFile(filepath.Join(root, ".vrt_file"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpDirs, err := FindTmpDirs(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(root, ".vrt_123")}; !slices.Equal(tmpDirs, want) {
+		t.Errorf("FindTmpDirs() = %v, want %v", tmpDirs, want)
+	}
+
+	frameDirs, err := FindFrameDirs(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(root, "vrt/.frames_menu_2"), filepath.Join(root, "vrt-results/.frames_menu_diff_3")}
+	if !slices.Equal(frameDirs, want) {
+		t.Errorf("FindFrameDirs() = %v, want %v", frameDirs, want)
+	}
+}
+
+func TestFindStaleFiles(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "vrt", "menu.avi")
+	recent := filepath.Join(dir, "manifest.json")
+	for _, file := range []string{old, recent} {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lastWeek := time.Now().Add(-7 * 24 * time.Hour)
+	if err := os.Chtimes(old, lastWeek, lastWeek); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := FindStaleFiles(dir, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{old}; !slices.Equal(files, want) {
+		t.Errorf("FindStaleFiles() = %v, want %v", files, want)
+	}
+
+	files, err = FindStaleFiles(filepath.Join(dir, "missing"), time.Now())
+	if err != nil || len(files) != 0 {
+		t.Errorf("FindStaleFiles() of a missing dir = %v, %v, want no files", files, err)
+	}
+}
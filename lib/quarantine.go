@@ -0,0 +1,77 @@
+package lib
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Quarantine marks a scene as known to be flaky. Quarantined scenes are still rendered and compared, but their
+// failures don't fail the test run.
+type Quarantine struct {
+	// Scene is a scene path relative from the project root (e.g. vrt/particles.tscn) to quarantine all of its
+	// variants and locales, or a single target without file extension (e.g. vrt/particles.rain.de).
+	Scene string `json:"scene"`
+	// Since is the date the scene was quarantined, formatted as YYYY-MM-DD.
+	Since  string `json:"since"`
+	Reason string `json:"reason"`
+}
+
+func (q Quarantine) validate() error {
+	if q.Scene == "" {
+		return fmt.Errorf("quarantined scene must not be empty")
+	}
+	if _, err := time.Parse(time.DateOnly, q.Since); err != nil {
+		return fmt.Errorf("invalid quarantine date %q for scene %s: use YYYY-MM-DD", q.Since, q.Scene)
+	}
+	return nil
+}
+
+// LoadQuarantineFile reads a quarantine list with one scene per line in the format
+//
+//	vrt/particles.tscn 2024-05-01 flickers on CI GPUs
+//
+// i.e. the scene, the date it was quarantined and the reason. Empty lines and lines starting with # are ignored.
+func LoadQuarantineFile(path string) ([]Quarantine, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading quarantine file %s: %v", path, err)
+	}
+	defer file.Close()
+
+	var quarantine []Quarantine
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("invalid line %d in quarantine file %s: use \"<scene> <YYYY-MM-DD> <reason>\"", lineNumber, path)
+		}
+		q := Quarantine{Scene: fields[0], Since: fields[1], Reason: strings.Join(fields[2:], " ")}
+		if err := q.validate(); err != nil {
+			return nil, fmt.Errorf("invalid line %d in quarantine file %s: %v", lineNumber, path, err)
+		}
+		quarantine = append(quarantine, q)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading quarantine file %s: %v", path, err)
+	}
+	return quarantine, nil
+}
+
+// FindQuarantine returns the quarantine of the first of names that is quarantined.
+func FindQuarantine(quarantine []Quarantine, names ...string) (Quarantine, bool) {
+	for _, q := range quarantine {
+		for _, name := range names {
+			if q.Scene == name {
+				return q, true
+			}
+		}
+	}
+	return Quarantine{}, false
+}
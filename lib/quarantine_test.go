@@ -0,0 +1,57 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadQuarantineFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vrt-quarantine.txt")
+	content := "# known flaky scenes\n\nvrt/particles.tscn 2024-05-01 flickers on CI GPUs\nvrt/menu.de  2024-06-12   font fallback differs\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	quarantine, err := LoadQuarantineFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Quarantine{
+		{Scene: "vrt/particles.tscn", Since: "2024-05-01", Reason: "flickers on CI GPUs"},
+		{Scene: "vrt/menu.de", Since: "2024-06-12", Reason: "font fallback differs"},
+	}
+	if len(quarantine) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(quarantine), len(want), quarantine)
+	}
+	for i := range want {
+		if quarantine[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, quarantine[i], want[i])
+		}
+	}
+}
+
+func TestLoadQuarantineFileInvalid(t *testing.T) {
+	for _, content := range []string{
+		"vrt/particles.tscn 2024-05-01\n",
+		"vrt/particles.tscn yesterday flickers\n",
+	} {
+		path := filepath.Join(t.TempDir(), "vrt-quarantine.txt")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadQuarantineFile(path); err == nil {
+			t.Errorf("expected an error for %q", content)
+		}
+	}
+}
+
+func TestFindQuarantine(t *testing.T) {
+	quarantine := []Quarantine{{Scene: "vrt/particles.tscn", Since: "2024-05-01", Reason: "flickers"}}
+	if _, ok := FindQuarantine(quarantine, "vrt/particles.rain", "vrt/particles.tscn"); !ok {
+		t.Error("expected the scene file to quarantine its variants")
+	}
+	if _, ok := FindQuarantine(quarantine, "vrt/menu", "vrt/menu.tscn"); ok {
+		t.Error("expected vrt/menu not to be quarantined")
+	}
+}
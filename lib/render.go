@@ -1,6 +1,8 @@
 package lib
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -15,37 +17,114 @@ type RenderSceneArgs struct {
 	Verbose                  bool
 	Frames                   int
 	ProjectPath              string
+	// Env holds additional environment variables (key=value) for Godot.
+	Env []string
+	// GodotArgs are additional arguments for Godot, e.g. to force a rendering driver.
+	GodotArgs []string
+	// UserArgs are passed to the scene's scripts (after "--").
+	UserArgs []string
 }
 
-func RenderScene(args RenderSceneArgs) (string, error) {
+type RenderResult struct {
+	OutputFile string
+	// Stdout and Stderr hold Godot's output while rendering the scene.
+	Stdout string
+	Stderr string
+}
+
+// TimeoutError is returned by RenderScene if Godot didn't finish before the context's deadline.
+type TimeoutError struct {
+	Stdout string
+	Stderr string
+}
+
+func (e *TimeoutError) Error() string {
+	return "rendering timed out"
+}
+
+// RenderScene renders a scene into a video. If the output file is a .png, only the last frame is kept as a screenshot.
+func RenderScene(ctx context.Context, args RenderSceneArgs) (RenderResult, error) {
 	if err := os.MkdirAll(filepath.Dir(args.OutputFile), 0755); err != nil {
-		return "", fmt.Errorf("error creating dir: %v", err)
+		return RenderResult{}, fmt.Errorf("error creating dir: %v", err)
+	}
+
+	movieFile := args.OutputFile
+	screenshot := filepath.Ext(args.OutputFile) == ".png"
+	if screenshot {
+		// Godot's movie writer saves each frame as a separate png, so we let it write into a temporary directory.
+		framesDir, err := os.MkdirTemp(filepath.Dir(args.OutputFile), ".frames_")
+		if err != nil {
+			return RenderResult{}, fmt.Errorf("error creating frames dir: %v", err)
+		}
+		defer os.RemoveAll(framesDir)
+		movieFile = filepath.Join(framesDir, "frame.png")
 	}
 
-	a := []string{
+	a := append(slices.Clone(args.GodotArgs),
 		"--quit-after",
 		strconv.Itoa(args.Frames),
-		"--write-movie", args.OutputFile,
+		"--write-movie", movieFile,
 		args.SceneFileFromProjectRoot,
+	)
+	if len(args.UserArgs) > 0 {
+		a = append(append(a, "--"), args.UserArgs...)
 	}
 	if args.Verbose {
 		a = slices.Insert(a, 0, "--verbose")
 	}
-	stdout, stderr, err := executeCommandUnsafe(&args.ProjectPath, args.GodotBinary, a)
+	stdout, stderr, err := executeCommandContext(ctx, &args.ProjectPath, args.Env, args.GodotBinary, a)
 	if args.Verbose {
 		fmt.Println(stdout)
 		fmt.Println(stderr)
 	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return RenderResult{}, &TimeoutError{Stdout: stdout, Stderr: stderr}
+	}
 	if err != nil {
-		return "", fmt.Errorf("error rendering scene: %v %s", err, stderr)
+		return RenderResult{}, fmt.Errorf("error rendering scene: %v %s", err, stderr)
+	}
+	if screenshot {
+		if err := keepLastFrame(filepath.Dir(movieFile), args.OutputFile); err != nil {
+			return RenderResult{}, err
+		}
 	}
 	fileInfo, err := os.Stat(args.OutputFile)
 	if err != nil {
-		return "", fmt.Errorf("error getting rendered file info: %v", err)
+		return RenderResult{}, fmt.Errorf("error getting rendered file info: %v", err)
 	}
 	if fileInfo.Size() == 0 {
-		return "", fmt.Errorf("error: rendered file is empty")
+		return RenderResult{}, fmt.Errorf("error: rendered file is empty")
 	}
 
-	return args.OutputFile, nil
+	return RenderResult{OutputFile: args.OutputFile, Stdout: stdout, Stderr: stderr}, nil
+}
+
+// keepLastFrame moves the last png frame of a directory to outFile.
+func keepLastFrame(framesDir, outFile string) error {
+	frames, err := filepath.Glob(filepath.Join(framesDir, "*.png"))
+	if err != nil {
+		return fmt.Errorf("failed to list frame files: %v", err)
+	}
+	if len(frames) == 0 {
+		return fmt.Errorf("error: no frames rendered")
+	}
+	// Frame numbers are zero padded, so the last file name is the last frame.
+	slices.Sort(frames)
+	if err := os.Rename(frames[len(frames)-1], outFile); err != nil {
+		return fmt.Errorf("error saving screenshot: %v", err)
+	}
+	return nil
+}
+
+// WriteRenderLog saves Godot's output of a render to <resultDir><name>.log and returns the file's path.
+func WriteRenderLog(name, stdout, stderr, resultDir string) (string, error) {
+	outFile := fmt.Sprintf("%s%s%s", resultDir, name, ".log")
+	if err := os.MkdirAll(filepath.Dir(outFile), 0755); err != nil {
+		return "", fmt.Errorf("error creating dir: %v %s", err, resultDir)
+	}
+	content := fmt.Sprintf("--- stdout ---\n%s\n--- stderr ---\n%s\n", stdout, stderr)
+	if err := os.WriteFile(outFile, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("error writing log file: %v", err)
+	}
+	return outFile, nil
 }
@@ -7,18 +7,29 @@ import (
 	"strings"
 )
 
-func Validate(godotPath, projectPath string) error {
-	if err := VerifyGodotInstallation(godotPath); err != nil {
-		return err
+// Validate checks that everything godot-vrt needs is installed, and returns the version of Godot. Unsupported Godot
+// versions are only rejected if allowUnsupportedGodot is false. If display isn't nil, Validate also checks that Godot
+// can render on it.
+func Validate(godotPath, projectPath string, allowUnsupportedGodot bool, display *VirtualDisplay) (GodotVersion, error) {
+	version, err := VerifyGodotInstallation(godotPath, allowUnsupportedGodot)
+	if err != nil {
+		return version, err
 	}
 	if err := VerifyBinary("ffmpeg"); err != nil {
-		return err
+		return version, err
 	}
 
 	if err := VerifyFileExists(WithFolderSuffix(projectPath) + "project.godot"); err != nil {
-		return err
+		return version, err
 	}
-	return nil
+	if display != nil {
+		adapter, err := VerifyDisplay(godotPath, display)
+		if err != nil {
+			return version, err
+		}
+		fmt.Printf("Rendering on virtual display %s with %s\n", display.Display, adapter)
+	}
+	return version, nil
 }
 
 func VerifyFileExists(path string) error {
@@ -36,28 +47,33 @@ func VerifyBinary(binaryPath string) error {
 	return nil
 }
 
-var supportedVersions = []string{"4.4.1", "4.4", "4.3", "4.2.2", "4.1.4"}
-
-func VerifyGodotInstallation(godotPath string) error {
+func VerifyGodotInstallation(godotPath string, allowUnsupported bool) (GodotVersion, error) {
 	if err := VerifyBinary(godotPath); err != nil {
-		return err
+		return GodotVersion{}, err
 	}
 
-	versionResult, stderr, err := executeCommandUnsafe(nil, godotPath, []string{"--version", "--headless"})
+	versionResult, err := godotVersion(godotPath)
 	if err != nil {
-		return fmt.Errorf("error executing Godot: %v %s", err, stderr)
+		return GodotVersion{}, err
 	}
 	fmt.Println("Godot version: " + versionResult)
 
-	supportedVersion := false
-	for _, v := range supportedVersions {
-		if strings.HasPrefix(versionResult, v) && strings.Contains(versionResult, "stable") {
-			supportedVersion = true
-			break
-		}
+	version, err := ParseGodotVersion(versionResult)
+	if err == nil && version.Supported() {
+		return version, nil
 	}
-	if !supportedVersion {
-		return fmt.Errorf("godot version is currently not supported. Please install one of the stable versions %s and try again: %v %s", supportedVersions, err, stderr)
+	if allowUnsupported {
+		fmt.Println("⚠️ This Godot version is not supported, continuing anyway because of --allow-unsupported-godot")
+		return version, nil
 	}
-	return nil
+	return version, fmt.Errorf("godot version %s is currently not supported. Please install a stable version in one of the ranges %v and try again, "+
+		"or pass --allow-unsupported-godot to use it anyway (e.g. for custom builds and release candidates)", versionResult, supportedGodotVersions)
+}
+
+func godotVersion(godotPath string) (string, error) {
+	versionResult, stderr, err := executeCommandUnsafe(nil, godotPath, []string{"--version", "--headless"})
+	if err != nil {
+		return "", fmt.Errorf("error executing Godot: %v %s", err, stderr)
+	}
+	return strings.TrimSpace(versionResult), nil
 }
@@ -0,0 +1,112 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var rootNodeNamePattern = regexp.MustCompile(`\[node name="([^"]+)"`)
+
+// GenerateVariantScene writes a temporary wrapper scene into the project that instances the given scene
+// and applies the variant's property overrides. It returns the wrapper's path relative from the project root,
+// and a function that removes the wrapper again.
+func GenerateVariantScene(projectPath, sceneFileFromProjectRoot string, variant Variant) (string, func(), error) {
+	scene, err := os.ReadFile(WithFolderSuffix(projectPath) + sceneFileFromProjectRoot)
+	if err != nil {
+		return "", nil, fmt.Errorf("error reading scene: %v", err)
+	}
+	// We keep the original root node name, because scripts may refer to it.
+	rootName := strings.TrimSuffix(filepath.Base(sceneFileFromProjectRoot), ".tscn")
+	if m := rootNodeNamePattern.FindSubmatch(scene); m != nil {
+		rootName = string(m[1])
+	}
+
+	content, err := variantSceneContent(sceneFileFromProjectRoot, rootName, variant)
+	if err != nil {
+		return "", nil, fmt.Errorf("error generating variant %s: %v", variant.Name, err)
+	}
+
+	dir, err := os.MkdirTemp(projectPath, ".vrt_variant_")
+	if err != nil {
+		return "", nil, fmt.Errorf("error creating variant dir: %v", err)
+	}
+	cleanup := func() {
+		if err := os.RemoveAll(dir); err != nil {
+			fmt.Printf("error removing variant dir: %v", err)
+		}
+	}
+
+	wrapper := filepath.Join(dir, fmt.Sprintf("%s.%s.tscn", strings.TrimSuffix(filepath.Base(sceneFileFromProjectRoot), ".tscn"), variant.Name))
+	if err := os.WriteFile(wrapper, []byte(content), 0644); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("error writing variant scene: %v", err)
+	}
+
+	return filepath.ToSlash(filepath.Join(filepath.Base(dir), filepath.Base(wrapper))), cleanup, nil
+}
+
+func variantSceneContent(sceneFileFromProjectRoot, rootName string, variant Variant) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("[gd_scene load_steps=2 format=3]\n\n")
+	sb.WriteString(fmt.Sprintf("[ext_resource type=\"PackedScene\" path=\"res://%s\" id=\"1_scene\"]\n\n", filepath.ToSlash(sceneFileFromProjectRoot)))
+	sb.WriteString(fmt.Sprintf("[node name=\"%s\" instance=ExtResource(\"1_scene\")]\n", rootName))
+
+	// The root node's properties have to be written right below the instance, all other nodes follow in a stable order.
+	if err := writeProperties(&sb, variant.Overrides["."]); err != nil {
+		return "", err
+	}
+	var nodePaths []string
+	for nodePath := range variant.Overrides {
+		if nodePath != "." {
+			nodePaths = append(nodePaths, nodePath)
+		}
+	}
+	slices.Sort(nodePaths)
+
+	for _, nodePath := range nodePaths {
+		parent := path.Dir(strings.Trim(nodePath, "/"))
+		sb.WriteString(fmt.Sprintf("\n[node name=\"%s\" parent=\"%s\"]\n", path.Base(nodePath), parent))
+		if err := writeProperties(&sb, variant.Overrides[nodePath]); err != nil {
+			return "", fmt.Errorf("node %s: %v", nodePath, err)
+		}
+	}
+	return sb.String(), nil
+}
+
+func writeProperties(sb *strings.Builder, properties map[string]any) error {
+	var names []string
+	for name := range properties {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		value, err := godotValue(properties[name])
+		if err != nil {
+			return fmt.Errorf("property %s: %v", name, err)
+		}
+		sb.WriteString(fmt.Sprintf("%s = %s\n", name, value))
+	}
+	return nil
+}
+
+func godotValue(v any) (string, error) {
+	switch value := v.(type) {
+	case nil:
+		return "null", nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	case string:
+		return value, nil
+	default:
+		return "", fmt.Errorf("unsupported value %v: use a number, a boolean, or a string with a Godot value", v)
+	}
+}
@@ -0,0 +1,51 @@
+package lib
+
+import "testing"
+
+func TestVariantSceneContent(t *testing.T) {
+	variant := Variant{
+		Name: "half",
+		Overrides: map[string]map[string]any{
+			".":              {"visible": true},
+			"Bar":            {"value": float64(50), "tint_progress": "Color(1, 0, 0, 1)"},
+			"Margin/Label":   {"text": "\"50%\""},
+			"Margin/Nothing": {"modulate": nil},
+		},
+	}
+
+	got, err := variantSceneContent("ui/health_bar.tscn", "HealthBar", variant)
+	if err != nil {
+		t.Fatalf("variantSceneContent() error = %v", err)
+	}
+
+	want := `[gd_scene load_steps=2 format=3]
+
+[ext_resource type="PackedScene" path="res://ui/health_bar.tscn" id="1_scene"]
+
+[node name="HealthBar" instance=ExtResource("1_scene")]
+visible = true
+
+[node name="Bar" parent="."]
+tint_progress = Color(1, 0, 0, 1)
+value = 50
+
+[node name="Label" parent="Margin"]
+text = "50%"
+
+[node name="Nothing" parent="Margin"]
+modulate = null
+`
+	if got != want {
+		t.Errorf("variantSceneContent() got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestVariantSceneContentRejectsUnsupportedValues(t *testing.T) {
+	variant := Variant{
+		Name:      "broken",
+		Overrides: map[string]map[string]any{"Bar": {"value": []any{1, 2}}},
+	}
+	if _, err := variantSceneContent("ui/health_bar.tscn", "HealthBar", variant); err == nil {
+		t.Error("variantSceneContent() expected an error for a list value")
+	}
+}